@@ -0,0 +1,729 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+// command is a goxisbuilder subcommand with its own flag set.
+type command struct {
+	name    string
+	summary string
+	run     func(fs *flag.FlagSet, args []string)
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"build", "Build the ACAP application in Docker (default command).", runBuild},
+		{"install", "Install a built .eap on the camera.", runInstall},
+		{"start", "Start the application on the camera.", runControl("start", (*builder.Camera).Start)},
+		{"stop", "Stop the application on the camera.", runControl("stop", (*builder.Camera).Stop)},
+		{"restart", "Restart the application on the camera.", runControl("restart", (*builder.Camera).Restart)},
+		{"remove", "Remove the application from the camera.", runControl("remove", (*builder.Camera).Remove)},
+		{"logs", "Follow the application log on the camera.", runLogs},
+		{"new", "Generate a new goxis app from a template ('new -list').", runNew},
+		{"inspect", "Show manifest details and compatibility without building.", runInspect},
+		{"doctor", "Check Docker, the application directory and the camera.", runDoctor},
+		{"config", "Show the effective configuration ('config print').", runConfig},
+		{"manifest", "Edit the manifest ('manifest set', 'param', 'version', 'migrate').", runManifest},
+		{"generate", "Generate Go code from the manifest ('generate params').", runGenerate},
+		{"compat", "Show which SDK, Ubuntu and schema to use for an AXIS OS version.", runCompat},
+		{"help", "Show help for a command.", runHelp},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func printCommands() {
+	fmt.Println("Usage: goxisbuilder <command> [flags]")
+	fmt.Println("\nCommands:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Println("\nRun 'goxisbuilder <command> -h' for the flags of a command.")
+}
+
+// newFlagSet creates the flag set of cmd with a usage message naming the command.
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: goxisbuilder %s [flags]\n\n%s\n\nFlags:\n", cmd.name, cmd.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// options holds the values of all flags that map onto BuildConfiguration.
+type options struct {
+	appDirectory string
+	manifestPath string
+	ip           string
+	pwd          string
+	arch         string
+	sdkVersion   string
+	ubuntu       string
+	dockerFile   string
+	filesToAdd   string
+	ignoreDirs   string
+	tags         string
+	ldflags      string
+	gcflags      string
+	ldVars       string
+	goEnv        string
+	buildNumber  string
+	goVersion    string
+	goToolchain  string
+	firmware     string
+	compatFile   string
+	targets      string
+	output       string
+	jobs         int
+	buildTimeout time.Duration
+	copyTimeout  time.Duration
+	doStart      bool
+	doInstall    bool
+	notCopy      bool
+	prune        bool
+	watch        bool
+	upx          bool
+	notices      bool
+	trimpath     bool
+	release      bool
+
+	compat *builder.CompatDB
+	out    io.Writer
+}
+
+func (o *options) addAppFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.appDirectory, "appdir", "", "The path to the application directory from which to build, or blank if the current directory is the application directory.")
+	fs.StringVar(&o.manifestPath, "manifest", "manifest.json", "The path to the manifest file. Defaults to 'manifest.json'.")
+}
+
+func (o *options) addCameraFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.ip, "ip", "", "The IP address of the camera where the EAP application is installed. (blank = "+envCameraIp+")")
+	fs.StringVar(&o.pwd, "pwd", "", "The root password for the camera where the EAP application is installed. (blank = "+envCameraPassword+", netrc file or prompt)")
+}
+
+func (o *options) addTargetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.arch, "arch", "aarch64", "The arch for the ACAP application: 'aarch64', 'armv7hf', a space- or comma-separated list of both, or 'all'.")
+	fs.StringVar(&o.sdkVersion, "sdk", "", "The version of the SDK to use. (blank = 12.7.0)")
+	fs.StringVar(&o.ubuntu, "ubunutu", "", "The Ubunut version to use. (blank = 24.04)")
+	fs.StringVar(&o.firmware, "target-firmware", "", "The AXIS OS version to build for, e.g. 11.11. Picks the SDK, Ubuntu version and manifest schema, unless set.")
+	fs.StringVar(&o.compatFile, "compat", "", "A compatibility file merged into the built-in SDK, schema and chip data, after "+builder.UserCompatFile()+" if it exists.")
+	fs.StringVar(&o.targets, "target", "", "Build only these targets of the goxis.yaml build matrix. (target1 target2 ...), blank builds all")
+}
+
+func (o *options) addBuildFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.dockerFile, "dockerfile", "", "Use a custom docker file'.")
+	fs.BoolVar(&o.doStart, "start", false, "Set to true to start the application after installation.")
+	fs.BoolVar(&o.doInstall, "install", false, "Set to true to install the application on the camera.")
+	fs.BoolVar(&o.notCopy, "nocopy", false, "Set to true if you dont want to copy the eap file to host machine.")
+	fs.BoolVar(&o.prune, "prune", false, "Set to true execute 'docker system prune -f' after build.")
+	fs.BoolVar(&o.watch, "watch", false, "Set to true to monitor the package log after building.")
+	fs.BoolVar(&o.upx, "upx", true, "Enable UPX compression of the Go binary (pass -upx=false to disable).")
+	fs.BoolVar(&o.notices, "notices", true, "Add the licenses of the Go modules as THIRD_PARTY_NOTICES to the eap (pass -notices=false to disable).")
+	fs.StringVar(&o.filesToAdd, "files", "", "Add additional files to the container. (filename1 filename2 directory ...), files need to be in appdir")
+	fs.StringVar(&o.ignoreDirs, "ignore", "", "Ignore directories in the appdir. (directory1 directory2 ...), directories need to be in appdir")
+	fs.DurationVar(&o.buildTimeout, "buildtimeout", 0, "Abort the Docker image build after this duration, e.g. '30m'. (0 = no limit)")
+	fs.DurationVar(&o.copyTimeout, "copytimeout", 10*time.Minute, "Abort copying the eap files out of the container after this duration.")
+	fs.IntVar(&o.jobs, "jobs", 4, "The number of builds to run at once when building several architectures, targets or applications.")
+	fs.StringVar(&o.ldflags, "ldflags", "", "Additional flags for 'go build -ldflags', added after the default '-s -w'.")
+	fs.StringVar(&o.gcflags, "gcflags", "", "Flags for 'go build -gcflags'.")
+	fs.StringVar(&o.ldVars, "X", "", "Set string variables with 'go build -ldflags -X'. (importpath.name=value ...)")
+	fs.StringVar(&o.goEnv, "env", "", "Environment variables for 'go build'. (NAME=value ...)")
+	fs.BoolVar(&o.trimpath, "trimpath", false, "Build with 'go build -trimpath'.")
+	fs.StringVar(&o.goVersion, "go", "", "The Go version to build with, e.g. 1.23.4. (blank = toolchain or go line of go.mod)")
+	fs.StringVar(&o.goToolchain, "gotoolchain", "", "A local Go archive (go1.23.4.linux-amd64.tar.gz) or a directory of them, installed instead of downloading Go.")
+	fs.BoolVar(&o.release, "release", false, "Refuse to build from a git working tree with uncommitted changes.")
+	fs.StringVar(&o.buildNumber, "buildnumber", "", "Stamp a dev build number into the manifest version, e.g. 42 builds 1.2.3 as 1.2.3-dev.42.")
+	fs.StringVar(&o.tags, "tags", "", "Go build tags to pass to 'go build -tags'. Accepts space- or comma-separated values; normalized to comma-separated.")
+}
+
+func (o *options) addOutputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.output, "output", "human", "The output format: 'human' or 'json' for newline-delimited JSON events on stdout.")
+}
+
+// addAllFlags registers every flag of the build command.
+func (o *options) addAllFlags(fs *flag.FlagSet) {
+	o.addAppFlags(fs)
+	o.addCameraFlags(fs)
+	o.addTargetFlags(fs)
+	o.addBuildFlags(fs)
+	o.addOutputFlags(fs)
+}
+
+// setOutput applies -output.
+func (o *options) setOutput() {
+	out, err := setOutputFormat(o.output)
+	if err != nil {
+		handleError("Invalid output format", err)
+	}
+	o.out = out
+}
+
+// stdout returns the writer of the human output, stdout unless -output
+// moved it.
+func (o *options) stdout() io.Writer {
+	if o.out == nil {
+		return os.Stdout
+	}
+	return o.out
+}
+
+// parse parses args into fs and fills every flag not given on the command
+// line from the project config of the application directory.
+func (o *options) parse(fs *flag.FlagSet, args []string) map[string]string {
+	sources, rest := o.parseArgs(fs, args)
+	if len(rest) > 0 {
+		fmt.Printf("Unexpected arguments: %s\n\n", strings.Join(rest, " "))
+		fs.Usage()
+		os.Exit(1)
+	}
+	return sources
+}
+
+// parseArgs is parse for commands with arguments, which may come before,
+// between or after the flags. It returns the arguments.
+func (o *options) parseArgs(fs *flag.FlagSet, args []string) (map[string]string, []string) {
+	rest := parseInterspersed(fs, args)
+	sources, err := applyProjectConfig(fs, o.appDirectory)
+	if err != nil {
+		handleError("Failed to load project config", err)
+	}
+	return sources, rest
+}
+
+// parseInterspersed parses the flags of args into fs and returns the other
+// arguments, in order. Everything after "--" is an argument.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var rest []string
+	for {
+		fs.Parse(args)
+		remaining := fs.Args()
+		if len(remaining) == 0 {
+			return rest
+		}
+		if i := len(args) - len(remaining); i > 0 && args[i-1] == "--" {
+			return append(rest, remaining...)
+		}
+		rest = append(rest, remaining[0])
+		args = remaining[1:]
+	}
+}
+
+// loadManifest loads the manifest of the application directory.
+func (o *options) loadManifest() *axmanifest.ApplicationManifestSchema {
+	manifestPathFull := path.Join(o.appDirectory, o.manifestPath)
+	amf, err := axmanifest.LoadManifest(manifestPathFull)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to load manifest from %s", manifestPathFull), err)
+	}
+	return amf
+}
+
+// buildConfiguration creates the BuildConfiguration from the parsed flags.
+func (o *options) buildConfiguration() *builder.BuildConfiguration {
+	amf := o.loadManifest()
+
+	buildConfig := &builder.BuildConfiguration{
+		AppDirectory: o.appDirectory,
+		Arch:         o.arch,
+		Manifest:     amf,
+		ManifestPath: o.manifestPath,
+		Ip:           o.ip,
+		Pwd:          o.pwd,
+		DoStart:      o.doStart,
+		DoInstall:    o.doInstall,
+		NotCopy:      o.notCopy,
+
+		Watch:         o.watch,
+		Dockerfile:    o.dockerFile,
+		FilesToAdd:    o.filesToAdd,
+		Prune:         o.prune,
+		SdkVersion:    o.sdkVersion,
+		UbunutVersion: o.ubuntu,
+		IgnoreDirs:    strings.Fields(o.ignoreDirs),
+		// Normalize tags to the modern, comma-separated form used by Go
+		BuildTags: builder.NormalizeGoBuildTags(o.tags),
+		EnableUpx: o.upx,
+		Notices:   o.notices,
+		LdFlags:   o.ldflags,
+		GcFlags:   o.gcflags,
+		LdVars:    strings.Fields(o.ldVars),
+		GoEnv:     strings.Fields(o.goEnv),
+		TrimPath:  o.trimpath,
+
+		Release:     o.release,
+		BuildNumber: o.buildNumber,
+		GoVersion:   o.goVersion,
+		GoToolchain: o.goToolchain,
+
+		TargetFirmware: o.firmware,
+		Compat:         o.compatDB(),
+
+		BuildTimeout: o.buildTimeout,
+		CopyTimeout:  o.copyTimeout,
+		Log:          o.stdout(),
+		OutputDir:    "build",
+	}
+	return buildConfig
+}
+
+// buildConfigurations creates one BuildConfiguration per goxis.yaml target
+// and architecture of -arch.
+func (o *options) buildConfigurations() []*builder.BuildConfiguration {
+	archs, err := builder.ParseArchitectures(o.arch)
+	if err != nil {
+		handleError("Architecture invalid", err)
+	}
+
+	targets, err := loadBuildTargets(o.appDirectory, o.targets)
+	if err != nil {
+		handleError("Failed to load build targets", err)
+	}
+	if len(targets) == 0 {
+		targets = []buildTarget{{}}
+	}
+
+	base := o.buildConfiguration()
+	var configs []*builder.BuildConfiguration
+	for _, target := range targets {
+		targetConfig := *base
+		if err := target.apply(&targetConfig); err != nil {
+			handleError("Invalid build target", err)
+		}
+		if targetConfig.TargetFirmware != "" {
+			rec, err := o.compatDB().ConfigureFirmware(&targetConfig)
+			if err != nil {
+				handleError("Unsupported target firmware", err)
+			}
+			fmt.Fprintf(o.stdout(), "Target AXIS OS %s: SDK %s, manifest schema up to %s\n", targetConfig.TargetFirmware, rec.Sdk.Version, rec.Schema.Version)
+		}
+		// Configure SDK and architecture for the specific app
+		builder.ConfigureSdk(&targetConfig)
+		fmt.Fprintln(o.stdout(), "Using SDK:", targetConfig.Sdk)
+		fmt.Fprintln(o.stdout(), "Using SDK version:", targetConfig.Version)
+		fmt.Fprintln(o.stdout(), "Using Ubuntu version:", targetConfig.UbunutVersion)
+		for _, arch := range archs {
+			buildConfig := targetConfig
+			if err := builder.ConfigureArchitecture(arch, &buildConfig); err != nil {
+				handleError("Architecture invalid", err)
+			}
+			configs = append(configs, &buildConfig)
+		}
+	}
+	return configs
+}
+
+// compatDB loads the compatibility data once, with the file of -compat.
+func (o *options) compatDB() *builder.CompatDB {
+	if o.compat == nil {
+		db, err := builder.LoadCompatDB(o.compatFile)
+		if err != nil {
+			handleError("Failed to load compatibility data", err)
+		}
+		o.compat = db
+	}
+	return o.compat
+}
+
+// requireCamera resolves the camera credentials and exits when the camera address is missing.
+func (o *options) requireCamera() {
+	if err := resolveCredentials(&o.ip, &o.pwd); err != nil {
+		handleError("Failed to resolve camera credentials", err)
+	}
+	if o.ip == "" {
+		fmt.Fprintln(o.stdout(), "The camera IP address is missing, set it with -ip, "+envCameraIp+" or in "+projectConfigFile+".")
+		os.Exit(1)
+	}
+}
+
+// appLayoutProblems checks that dir contains a single application.
+func appLayoutProblems(dir string) []string {
+	var problems []string
+	for _, name := range []string{"go.mod", "LICENSE", "manifest.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); errors.Is(err, os.ErrNotExist) {
+			problems = append(problems, fmt.Sprintf("%s was not found", name))
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("failed to search for Go files: %v", err))
+	} else if len(files) == 0 {
+		problems = append(problems, "no Go (.go) files found")
+	}
+	return problems
+}
+
+func runBuild(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	o.addAllFlags(fs)
+	o.parse(fs, args)
+	o.setOutput()
+	if o.watch && events != nil {
+		fmt.Fprintln(o.out, "-watch can not be combined with -output json.")
+		os.Exit(1)
+	}
+	if o.doInstall || o.doStart || o.watch {
+		o.requireCamera()
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	cli, err := builder.NewDockerClient()
+	if err != nil {
+		handleError("Failed create new docker client", err)
+	}
+
+	// A directory that is not an app itself may be a workspace of several apps
+	var configs []*builder.BuildConfiguration
+	root := o.appDirectory
+	if root == "" {
+		root = "."
+	}
+	if problems := appLayoutProblems(root); len(problems) > 0 {
+		apps, err := discoverApps(root)
+		if err != nil {
+			handleError("Failed to search for applications", err)
+		}
+		if len(apps) > 0 {
+			fmt.Fprintf(o.out, "Found %d applications in %s\n", len(apps), root)
+			configs = workspaceConfigs(root, apps, args, o.out)
+		} else if o.appDirectory == "" {
+			for _, problem := range problems {
+				fmt.Fprintf(o.out, "In the current directory %s.\n", problem)
+			}
+			fmt.Fprintln(o.out, "Please specify the app directory with -appdir, or create the missing files if you are inside the project directory (go mod init <module-path> for go.mod).")
+			os.Exit(1)
+		}
+	}
+
+	if configs == nil {
+		configs = o.buildConfigurations()
+	}
+	if len(configs) == 1 {
+		buildConfig := configs[0]
+		if events != nil {
+			// The builder sends the output as log events
+			buildConfig.Log = nil
+		}
+		if _, err := builder.New(cli, buildConfig, eventSink()).Build(ctx); err != nil {
+			handleError("Failed to build and run container", err)
+		}
+		if buildConfig.Prune {
+			pruneDocker(o.out)
+		}
+
+		printCompatibility(o.out, o.compatDB(), buildConfig)
+		listEapDirectory(o.out, buildConfig.OutputDir)
+
+		if buildConfig.Watch {
+			watchPackageLog(buildConfig)
+		}
+		return
+	}
+
+	if o.doInstall || o.doStart || o.watch {
+		fmt.Fprintln(o.out, "-install, -start and -watch need a single build, select one application, architecture and target.")
+		os.Exit(1)
+	}
+	results := buildAll(ctx, cli, configs, o.jobs, o.out)
+	if o.prune {
+		pruneDocker(o.out)
+	}
+	for _, r := range results {
+		if r.Err == nil {
+			printCompatibility(o.out, o.compatDB(), r.Config)
+		}
+	}
+	if !printBuildSummary(o.out, results) {
+		os.Exit(1)
+	}
+}
+
+func runInstall(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	o.addAppFlags(fs)
+	o.addCameraFlags(fs)
+	o.addOutputFlags(fs)
+	eapPath := fs.String("eap", "", "The .eap file to install. (blank = newest .eap in ./build)")
+	start := fs.Bool("start", false, "Set to true to start the application after installation.")
+	o.parse(fs, args)
+	o.setOutput()
+	o.requireCamera()
+
+	if *eapPath == "" {
+		newest, err := newestEap("build")
+		if err != nil {
+			handleError("Failed to find an .eap file, build the app first or pass -eap", err)
+		}
+		*eapPath = newest
+	}
+
+	buildConfig := o.buildConfiguration()
+	buildConfig.DoInstall = true
+	buildConfig.DoStart = *start
+	if err := builder.New(nil, buildConfig, eventSink()).Deploy(*eapPath); err != nil {
+		handleError("Failed to deploy application", err)
+	}
+}
+
+// runControl returns the runner of a command that only calls control.cgi.
+func runControl(action string, control func(*builder.Camera, string) error) func(fs *flag.FlagSet, args []string) {
+	return func(fs *flag.FlagSet, args []string) {
+		o := &options{}
+		o.addAppFlags(fs)
+		o.addCameraFlags(fs)
+		o.parse(fs, args)
+		o.requireCamera()
+
+		appName := o.loadManifest().ACAPPackageConf.Setup.AppName
+		if err := control(builder.NewCamera(o.ip, o.pwd), appName); err != nil {
+			handleError(fmt.Sprintf("Failed to %s application", action), err)
+		}
+		fmt.Printf("Application %s: %s done\n", appName, action)
+	}
+}
+
+func runLogs(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	o.addAppFlags(fs)
+	o.addCameraFlags(fs)
+	o.parse(fs, args)
+	o.requireCamera()
+
+	watchPackageLog(o.buildConfiguration())
+}
+
+func runNew(fs *flag.FlagSet, args []string) {
+	var opts newProjectOptions
+	var list bool
+	var configFile string
+	fs.StringVar(&opts.ModuleName, "module", "", "The Go module path, e.g. github.com/username/repo. (blank = ask)")
+	fs.StringVar(&opts.AppName, "appname", "", "The appName of the manifest. (blank = ask)")
+	fs.StringVar(&opts.FriendlyName, "friendlyname", "", "The friendlyName of the manifest. (blank = ask)")
+	fs.StringVar(&opts.Vendor, "vendor", "", "The vendor of the manifest. (blank = ask)")
+	fs.StringVar(&opts.template, "template", "", "The project template, a name from -list or a template directory. (blank = ask, minimal without a terminal)")
+	fs.StringVar(&opts.templateDir, "template-dir", "", "A directory of project templates, used before "+userTemplateDir()+" and the built-in ones.")
+	fs.StringVar(&opts.schema, "schema", "", "The schemaVersion of the manifest. (blank = the newest of the SDK, "+defaultSchemaVersion()+" for the default SDK)")
+	fs.StringVar(&opts.firmware, "target-firmware", "", "The AXIS OS version to build for, e.g. 11.11. Picks the SDK and manifest schema and pins them in "+projectConfigFile+". (blank = the default SDK)")
+	fs.StringVar(&opts.compatFile, "compat", "", "A compatibility file merged into the built-in SDK and schema data.")
+	fs.StringVar(&opts.goxis, "goxis", "", "The goxis version to require. (blank = "+goxisVersion()+", the one of goxisbuilder)")
+	fs.StringVar(&opts.license, "license", "", "The LICENSE, one of the SPDX ids "+strings.Join(licenseIDs(), ", ")+" for the vendor, or a file. (blank = ask, "+defaultLicense+" without a terminal)")
+	fs.StringVar(&opts.dir, "dir", "", "The directory to generate into, existing files in it are kept. (blank = the app name)")
+	fs.StringVar(&configFile, "config", "", "A YAML file with values of these flags, keyed by flag name. Flags override it.")
+	fs.BoolVar(&list, "list", false, "List the project templates.")
+	fs.Parse(args)
+	if configFile != "" {
+		_, err := applyConfigFile(fs, configFile, func(name string) bool {
+			return name != "config" && fs.Lookup(name) != nil
+		})
+		if err != nil {
+			handleError("Failed to load the config file", err)
+		}
+	}
+	if list {
+		var dirs []string
+		if opts.templateDir != "" {
+			dirs = append(dirs, opts.templateDir)
+		}
+		templates, err := loadTemplates(dirs...)
+		if err != nil {
+			handleError("Failed to load templates", err)
+		}
+		printTemplates(templates)
+		return
+	}
+	createNewProject(opts)
+}
+
+func runInspect(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	o.addAppFlags(fs)
+	o.addTargetFlags(fs)
+	o.addOutputFlags(fs)
+	o.parse(fs, args)
+	o.setOutput()
+
+	configs := o.buildConfigurations()
+	buildConfig := configs[0]
+
+	setup := buildConfig.Manifest.ACAPPackageConf.Setup
+	fmt.Fprintln(o.out, "\nManifest:", path.Join(o.appDirectory, o.manifestPath))
+	fmt.Fprintln(o.out, "     App name:", setup.AppName)
+	fmt.Fprintln(o.out, "     Friendly name:", setup.FriendlyName)
+	fmt.Fprintln(o.out, "     Vendor:", setup.Vendor)
+	fmt.Fprintln(o.out, "     Version:", setup.Version)
+	fmt.Fprintln(o.out, "     Run mode:", setup.RunMode)
+	fmt.Fprintln(o.out, "     Schema version:", buildConfig.Manifest.SchemaVersion)
+	valid := true
+	for _, bc := range configs {
+		printCompatibility(o.out, o.compatDB(), bc)
+		if err := builder.ValidateManifest(bc); err != nil {
+			valid = false
+			fmt.Fprintf(o.out, "\n%s: %v\n", bc.Name(), err)
+			events.Emit(builder.Event{Type: builder.EventError, Build: bc.Name(), Message: "Invalid manifest", Error: err.Error()})
+		}
+	}
+	if !valid {
+		os.Exit(1)
+	}
+}
+
+func runDoctor(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	o.addAllFlags(fs)
+	fs.Parse(args)
+
+	failed := false
+	report := func(name string, err error) {
+		if err != nil {
+			failed = true
+			fmt.Printf("[FAIL] %s: %v\n", name, err)
+			return
+		}
+		fmt.Printf("[ OK ] %s\n", name)
+	}
+
+	cli, err := builder.NewDockerClient()
+	if err == nil {
+		var version string
+		version, err = dockerServerVersion(context.Background(), cli)
+		if err == nil {
+			fmt.Println("       Docker server version:", version)
+		}
+	}
+	report("Docker daemon reachable", err)
+
+	dir := o.appDirectory
+	if dir == "" {
+		dir = "."
+	}
+	var layoutErr error
+	if problems := appLayoutProblems(dir); len(problems) > 0 {
+		layoutErr = errors.New(strings.Join(problems, ", "))
+	}
+	report("Application directory "+dir, layoutErr)
+
+	_, err = applyProjectConfig(fs, o.appDirectory)
+	report("Project config "+projectConfigFile, err)
+
+	manifestPathFull := path.Join(o.appDirectory, o.manifestPath)
+	_, err = axmanifest.LoadManifest(manifestPathFull)
+	report("Manifest "+manifestPathFull, err)
+
+	err = resolveCredentials(&o.ip, &o.pwd)
+	if err != nil {
+		report("Camera credentials", err)
+	} else if o.ip != "" {
+		report("Camera "+o.ip+" reachable", builder.NewCamera(o.ip, o.pwd).Ping())
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func runConfig(fs *flag.FlagSet, args []string) {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Println("Usage: goxisbuilder config print [flags]")
+		os.Exit(1)
+	}
+	o := &options{}
+	o.addAllFlags(fs)
+	sources := o.parse(fs, args[1:])
+	printProjectConfig(fs, sources)
+
+	targets, err := loadBuildTargets(o.appDirectory, o.targets)
+	if err != nil {
+		handleError("Failed to load build targets", err)
+	}
+	printBuildTargets(targets)
+}
+
+func runCompat(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	var firmware, chip string
+	fs.StringVar(&firmware, "firmware", "", "The AXIS OS version, e.g. 11.11. (blank = list all data)")
+	fs.StringVar(&chip, "chip", "", "The chip of the device, e.g. ARTPEC-8.")
+	fs.StringVar(&o.arch, "arch", "", "The architecture of the device, instead of -chip.")
+	fs.StringVar(&o.compatFile, "compat", "", "A compatibility file merged into the built-in data, after "+builder.UserCompatFile()+" if it exists.")
+	fs.Parse(args)
+	db := o.compatDB()
+
+	arch := o.arch
+	if chip != "" {
+		name, chipArch, ok := db.FindChip(chip)
+		if !ok {
+			handleError("Unknown chip", fmt.Errorf("%s is not in the compatibility data, pass -arch instead", chip))
+		}
+		chip, arch = name, chipArch
+	} else if arch != "" {
+		if _, ok := db.Architectures[arch]; !ok {
+			handleError("Unknown architecture", fmt.Errorf("%s is not in the compatibility data", arch))
+		}
+	}
+
+	if firmware == "" {
+		printCompatDB(db)
+		return
+	}
+	rec, err := db.ForFirmware(firmware)
+	if err != nil {
+		handleError("Unsupported firmware", err)
+	}
+	printRecommendation(db, rec, chip, arch)
+}
+
+func runHelp(fs *flag.FlagSet, args []string) {
+	if len(args) > 0 {
+		if cmd := findCommand(args[0]); cmd != nil {
+			cmd.run(newFlagSet(cmd), []string{"-h"})
+			return
+		}
+	}
+	printCommands()
+}
+
+// newestEap returns the most recently modified .eap file in dir.
+func newestEap(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.eap"))
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no .eap file in %s", dir)
+	}
+	newest := ""
+	var newestTime time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		if newest == "" || info.ModTime().After(newestTime) {
+			newest, newestTime = file, info.ModTime()
+		}
+	}
+	return newest, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantRest    []string
+		wantType    string
+		wantDefault string
+	}{
+		{name: "flags first", args: []string{"-type", "int", "add", "Foo"}, wantRest: []string{"add", "Foo"}, wantType: "int"},
+		{name: "flags last", args: []string{"add", "Foo", "-type", "int", "-default", "3"}, wantRest: []string{"add", "Foo"}, wantType: "int", wantDefault: "3"},
+		{name: "flags between", args: []string{"add", "-type=int", "Foo", "-default", "3"}, wantRest: []string{"add", "Foo"}, wantType: "int", wantDefault: "3"},
+		{name: "no arguments", args: []string{"-type", "int"}, wantType: "int"},
+		{name: "terminator", args: []string{"add", "--", "-Foo", "-type"}, wantRest: []string{"add", "-Foo", "-type"}},
+		{name: "dash value", args: []string{"add", "Foo", "-default", "--"}, wantRest: []string{"add", "Foo"}, wantDefault: "--"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			paramType := fs.String("type", "", "")
+			paramDefault := fs.String("default", "", "")
+			rest := parseInterspersed(fs, tt.args)
+			if !reflect.DeepEqual(rest, tt.wantRest) || *paramType != tt.wantType || *paramDefault != tt.wantDefault {
+				t.Errorf("got %q, -type %q, -default %q, want %q, %q, %q", rest, *paramType, *paramDefault, tt.wantRest, tt.wantType, tt.wantDefault)
+			}
+		})
+	}
+}
+
+func TestFindCommand(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, cmd := range commands {
+		if seen[cmd.name] || cmd.summary == "" || cmd.run == nil {
+			t.Errorf("command %q is a duplicate or incomplete", cmd.name)
+		}
+		seen[cmd.name] = true
+		if findCommand(cmd.name) != cmd {
+			t.Errorf("findCommand(%q) does not find it", cmd.name)
+		}
+	}
+	if cmd := findCommand("frobnicate"); cmd != nil {
+		t.Errorf("findCommand(frobnicate) = %v", cmd.name)
+	}
+}
+
+func TestCommandDispatch(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, testApp("app"))
+	tests := []struct {
+		name   string
+		args   []string
+		wantOK bool
+		want   []string
+	}{
+		{"help flag", []string{"-h"}, true, []string{"Usage: goxisbuilder <command> [flags]", "  build ", "  manifest ", "  compat "}},
+		{"help command", []string{"help"}, true, []string{"Usage: goxisbuilder <command> [flags]"}},
+		{"help of a command", []string{"help", "compat"}, true, []string{"Usage: goxisbuilder compat [flags]", "-firmware"}},
+		{"unknown command", []string{"frobnicate"}, false, []string{`Unknown command "frobnicate"`, "  build "}},
+		{"flag of another command", []string{"compat", "-appdir", "app"}, false, []string{"flag provided but not defined: -appdir", "Usage: goxisbuilder compat [flags]"}},
+		{"build without a command", []string{"-appdir", "app", "-arch", "mips"}, false, []string{"Architecture invalid"}},
+		{"new with -newapp", []string{"-newapp", "-list"}, true, []string{"minimal", "built-in"}},
+		{"subcommand missing", []string{"manifest"}, false, []string{"Usage: goxisbuilder manifest <subcommand>", "manifest migrate"}},
+		{"unknown subcommand", []string{"generate", "mocks"}, false, []string{"generate params"}},
+		{"subcommand", []string{"compat", "-firmware", "11.11"}, true, []string{"1.15"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, ok := runGoxisbuilder(t, dir, tt.args...)
+			if ok != tt.wantOK {
+				t.Errorf("goxisbuilder %v succeeded %v, want %v:\n%s", tt.args, ok, tt.wantOK, out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("goxisbuilder %v output has no %q:\n%s", tt.args, want, out)
+				}
+			}
+		})
+	}
+}
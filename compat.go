@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+// printCompatDB prints the SDK releases, manifest schemas and chips of db.
+func printCompatDB(db *builder.CompatDB) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SDK\tVERSION\tAXIS OS\tUBUNTU")
+	for _, sdk := range db.SDKs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", sdk.Sdk, sdk.Version, db.Firmware(sdk), sdk.Ubuntu)
+	}
+	tw.Flush()
+
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCHEMA\tAXIS OS")
+	for _, schema := range db.Schemas {
+		fmt.Fprintf(tw, "%s\t%s and later\n", schema.Version, schema.Firmware)
+	}
+	tw.Flush()
+
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ARCH\tCHIPS")
+	for _, arch := range sortedArchs(db) {
+		fmt.Fprintf(tw, "%s\t%s\n", arch, strings.Join(db.Architectures[arch], ", "))
+	}
+	tw.Flush()
+}
+
+// printRecommendation prints what to build an AXIS OS version and chip or
+// architecture with.
+func printRecommendation(db *builder.CompatDB, rec builder.Recommendation, chip, arch string) {
+	device := ""
+	switch {
+	case chip != "":
+		device = fmt.Sprintf(" on %s (%s)", chip, arch)
+	case arch != "":
+		device = fmt.Sprintf(" on %s", arch)
+	}
+	fmt.Printf("AXIS OS %s%s:\n", rec.Firmware, device)
+	fmt.Printf("     SDK:             %s %s%s%s, compatible with AXIS OS %s\n", rec.Sdk.Sdk, Blue, rec.Sdk.Version, Reset, db.Firmware(rec.Sdk))
+	fmt.Printf("     Ubuntu:          %s%s%s\n", Blue, rec.Sdk.Ubuntu, Reset)
+	fmt.Printf("     Manifest schema: %s%s%s or older, %s introduced it\n", Blue, rec.Schema.Version, Reset, rec.Schema.Firmware)
+	if arch == "" {
+		for _, a := range sortedArchs(db) {
+			fmt.Printf("     Architecture:    %s for %s\n", a, strings.Join(db.Architectures[a], ", "))
+		}
+		arch = "<arch>"
+	}
+	fmt.Printf("     Build with:      %sgoxisbuilder build -arch %s -target-firmware %s%s\n", Green, arch, rec.Firmware, Reset)
+}
+
+func sortedArchs(db *builder.CompatDB) []string {
+	archs := make([]string, 0, len(db.Architectures))
+	for arch := range db.Architectures {
+		archs = append(archs, arch)
+	}
+	sort.Strings(archs)
+	return archs
+}
@@ -1,35 +1,190 @@
 package main
 
-import "github.com/Cacsjep/goxis/pkg/axmanifest"
-
-// BuildConfiguration defines the configuration parameters for building
-// the EAP application, including details such as architecture, manifest details,
-// and flags indicating whether to install the application, start it,
-// build examples, or watch logs.
-type BuildConfiguration struct {
-	Manifest      *axmanifest.ApplicationManifestSchema
-	ManifestPath  string
-	ImageName     string
-	Ip            string
-	Pwd           string
-	Arch          string
-	DoStart       bool
-	DoInstall     bool
-	NotCopy       bool
-	Prune         bool
-	AppDirectory  string
-	Sdk           string
-	UbunutVersion string
-	Version       string
-	GoArch        string
-	GoArm         string
-	CrossPrefix   string
-
-	Watch         bool
-	Dockerfile    string
-	FilesToAdd    string
-	SdkVersion    string
-	IgnoreDirs    []string
-	BuildTags     string
-	EnableUpx     bool
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigFile is the checked-in project file that is looked up in the
+// application directory. Its keys are the flag names, e.g. "sdk" or "tags".
+const projectConfigFile = "goxis.yaml"
+
+// Sources of a configuration value as shown by 'config print'.
+const (
+	sourceDefault = "default"
+	sourceFile    = projectConfigFile
+	sourceFlag    = "flag"
+)
+
+// projectConfigSkip lists flags that make no sense inside a project file.
+var projectConfigSkip = map[string]bool{
+	"appdir": true,
+}
+
+// projectConfigTargetsKey holds the build matrix, see loadBuildTargets.
+const projectConfigTargetsKey = "targets"
+
+// projectConfigAliases maps friendlier keys onto their flag names.
+var projectConfigAliases = map[string]string{
+	"ubuntu": "ubunutu",
+}
+
+// applyProjectConfig loads goxis.yaml from appDir and applies its values to
+// every flag of fs that was not given on the command line, so flags always
+// override the file. It returns the source of every flag in fs.
+func applyProjectConfig(fs *flag.FlagSet, appDir string) (map[string]string, error) {
+	data, configPath, err := readProjectConfig(appDir)
+	if err != nil || data == nil {
+		return flagSources(fs), err
+	}
+	return applyConfigFile(fs, configPath, func(name string) bool {
+		return name == projectConfigTargetsKey || isProjectConfigKey(name)
+	})
+}
+
+// flagSources returns the source of every flag in fs without a config file.
+func flagSources(fs *flag.FlagSet) map[string]string {
+	sources := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		sources[f.Name] = sourceDefault
+	})
+	fs.Visit(func(f *flag.Flag) {
+		sources[f.Name] = sourceFlag
+	})
+	return sources
+}
+
+// applyConfigFile applies the values of the YAML file at path to the flags
+// of fs that were not given on the command line and returns the source of
+// every flag in fs. Its keys are flag names or their aliases, known reports
+// whether the file may set one. Known keys of flags fs lacks are left alone.
+func applyConfigFile(fs *flag.FlagSet, path string, known func(name string) bool) (map[string]string, error) {
+	sources := flagSources(fs)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := key
+		if alias, ok := projectConfigAliases[key]; ok {
+			name = alias
+		}
+		if !known(name) {
+			return nil, fmt.Errorf("%s: unknown key %q", path, key)
+		}
+		if name == "pwd" {
+			return nil, fmt.Errorf("%s: the camera password must not be stored in the project file, use %s or a netrc file", path, envCameraPassword)
+		}
+		// Keys of other commands, e.g. 'sdk' for 'logs', and the targets are left alone
+		if fs.Lookup(name) == nil || sources[name] == sourceFlag {
+			continue
+		}
+		value, err := projectConfigValue(values[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", path, key, err)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", path, key, err)
+		}
+		sources[name] = sourceFile
+	}
+	return sources, nil
+}
+
+// readProjectConfig returns the content and path of goxis.yaml in appDir,
+// or nil content if there is none.
+func readProjectConfig(appDir string) ([]byte, string, error) {
+	configPath := filepath.Join(appDir, projectConfigFile)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, configPath, nil
+		}
+		return nil, configPath, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+	return data, configPath, nil
+}
+
+// isProjectConfigKey reports whether name is a flag of the build command,
+// which has every flag that can be set in the project config.
+func isProjectConfigKey(name string) bool {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	(&options{}).addAllFlags(fs)
+	return !projectConfigSkip[name] && fs.Lookup(name) != nil
+}
+
+// projectConfigValue converts a YAML value into the string form a flag
+// accepts. Lists become space separated, like on the command line.
+func projectConfigValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case bool, int, float64:
+		return fmt.Sprint(val), nil
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			s, err := projectConfigValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, " "), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// printProjectConfig shows the effective configuration of fs and where each
+// value came from. The camera password is never printed.
+func printProjectConfig(fs *flag.FlagSet, sources map[string]string) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	fs.VisitAll(func(f *flag.Flag) {
+		if projectConfigSkip[f.Name] {
+			return
+		}
+		value := f.Value.String()
+		if f.Name == "pwd" && value != "" {
+			value = "********"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Name, value, sources[f.Name])
+	})
+	tw.Flush()
+}
+
+// printBuildTargets shows the build matrix of goxis.yaml.
+func printBuildTargets(targets []buildTarget) {
+	if len(targets) == 0 {
+		return
+	}
+	fmt.Println("\nTargets:")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSDK\tUBUNTU\tFIRMWARE\tMANIFEST\tTAGS")
+	for _, t := range targets {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.Name, t.Sdk, t.Ubuntu, t.Firmware, t.Manifest, t.Tags)
+	}
+	tw.Flush()
 }
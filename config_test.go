@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestApplyProjectConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string // blank for no goxis.yaml
+		args    []string
+		want    map[string]string // flag values
+		sources map[string]string
+		wantErr string
+	}{
+		{
+			name:    "no config",
+			args:    []string{"-sdk", "1.15"},
+			want:    map[string]string{"sdk": "1.15", "arch": "aarch64"},
+			sources: map[string]string{"sdk": sourceFlag, "arch": sourceDefault},
+		},
+		{
+			name:    "flags override the file",
+			config:  "sdk: \"1.14\"\nubuntu: \"22.04\"\ntags: [a, b]\n",
+			args:    []string{"-sdk", "1.15"},
+			want:    map[string]string{"sdk": "1.15", "ubunutu": "22.04", "tags": "a b"},
+			sources: map[string]string{"sdk": sourceFlag, "ubunutu": sourceFile, "tags": sourceFile, "arch": sourceDefault},
+		},
+		{
+			name:   "targets are left to the build matrix",
+			config: "targets:\n  - name: axis11\n    firmware: \"11.11\"\n",
+			want:   map[string]string{"target-firmware": ""},
+		},
+		{name: "unknown key", config: "colour: blue\n", wantErr: `unknown key "colour"`},
+		{name: "appdir", config: "appdir: app\n", wantErr: `unknown key "appdir"`},
+		{name: "password", config: "pwd: secret\n", wantErr: "the camera password must not be stored in the project file"},
+		{name: "invalid value", config: "upx: maybe\n", wantErr: `key "upx"`},
+		{name: "invalid YAML", config: "sdk: [\n", wantErr: "failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.config != "" {
+				writeTestFiles(t, dir, map[string]string{projectConfigFile: tt.config})
+			}
+			fs := flag.NewFlagSet("build", flag.ContinueOnError)
+			(&options{}).addAllFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			sources, err := applyProjectConfig(fs, dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				if got := fs.Lookup(name).Value.String(); got != want {
+					t.Errorf("-%s = %q, want %q", name, got, want)
+				}
+			}
+			for name, want := range tt.sources {
+				if sources[name] != want {
+					t.Errorf("source of -%s = %s, want %s", name, sources[name], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/erikgeiser/promptkit/textinput"
+	"golang.org/x/term"
+)
+
+// Environment variables that provide the camera credentials.
+const (
+	envCameraIp       = "GOXIS_IP"
+	envCameraPassword = "GOXIS_PASSWORD"
+)
+
+// resolveCredentials fills in the camera address and password when they were
+// not given as flags. The address comes from GOXIS_IP, the password from
+// GOXIS_PASSWORD, a netrc file ($NETRC or ~/.netrc) or, on a terminal, a prompt.
+func resolveCredentials(ip, pwd *string) error {
+	if *ip == "" {
+		*ip = os.Getenv(envCameraIp)
+	}
+	if *pwd != "" || *ip == "" {
+		return nil
+	}
+	if *pwd = os.Getenv(envCameraPassword); *pwd != "" {
+		return nil
+	}
+
+	password, err := netrcPassword(*ip)
+	if err != nil {
+		return err
+	}
+	if password != "" {
+		*pwd = password
+		return nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	input := textinput.New(fmt.Sprintf("Root password for %s", *ip))
+	input.Hidden = true
+	password, err = input.RunPrompt()
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	*pwd = password
+	return nil
+}
+
+// netrcPath returns the path of the netrc file, $NETRC or ~/.netrc.
+func netrcPath() (string, error) {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".netrc"), nil
+}
+
+// netrcPassword looks up the root password of machine in the netrc file.
+// Entries for other logins are ignored, a 'default' entry matches any machine
+// without an entry of its own.
+func netrcPassword(machine string) (string, error) {
+	path, err := netrcPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the netrc file: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	type entry struct {
+		machine, login, password string
+		isDefault                bool
+	}
+	var entries []*entry
+	var current *entry
+	tokens := netrcTokens(string(data))
+	for i := 0; i < len(tokens); i++ {
+		value := ""
+		if i+1 < len(tokens) {
+			value = tokens[i+1]
+		}
+		switch tokens[i] {
+		case "machine":
+			current = &entry{machine: value}
+			entries = append(entries, current)
+			i++
+		case "default":
+			current = &entry{isDefault: true}
+			entries = append(entries, current)
+		case "login", "password", "account":
+			if current != nil && tokens[i] == "login" {
+				current.login = value
+			}
+			if current != nil && tokens[i] == "password" {
+				current.password = value
+			}
+			i++
+		}
+	}
+
+	for _, isDefault := range []bool{false, true} {
+		for _, e := range entries {
+			if e.isDefault == isDefault && (isDefault || e.machine == machine) && (e.login == "" || e.login == "root") {
+				return e.password, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// netrcTokens splits the content of a netrc file into its tokens. A token
+// may be quoted with '"' to contain spaces, '\' escapes the next character.
+// The definitions of macdef macros run until an empty line and are skipped.
+func netrcTokens(data string) []string {
+	var tokens []string
+	inMacro := false
+	for _, line := range strings.Split(data, "\n") {
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		for line = strings.TrimLeft(line, " \t\r"); line != ""; line = strings.TrimLeft(line, " \t\r") {
+			var token strings.Builder
+			quoted := line[0] == '"'
+			if quoted {
+				line = line[1:]
+			}
+			i := 0
+			for ; i < len(line); i++ {
+				c := line[i]
+				if c == '\\' && i+1 < len(line) {
+					i++
+					token.WriteByte(line[i])
+					continue
+				}
+				if (quoted && c == '"') || (!quoted && (c == ' ' || c == '\t' || c == '\r')) {
+					break
+				}
+				token.WriteByte(c)
+			}
+			if quoted && i < len(line) {
+				i++ // The closing quote
+			}
+			line = line[i:]
+			tokens = append(tokens, token.String())
+		}
+		if n := len(tokens); n >= 2 && tokens[n-2] == "macdef" {
+			tokens = tokens[:n-2]
+			inMacro = true
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNetrcPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		netrc   string
+		machine string
+		want    string
+	}{
+		{"machine", "machine 10.0.0.48 login root password pass1", "10.0.0.48", "pass1"},
+		{"multi line", "machine 10.0.0.48\n  login root\n  password pass1\n", "10.0.0.48", "pass1"},
+		{"other machine", "machine 10.0.0.1 login root password pass1", "10.0.0.48", ""},
+		{"other login", "machine 10.0.0.48 login admin password pass1", "10.0.0.48", ""},
+		{"no login", "machine 10.0.0.48 password pass1", "10.0.0.48", "pass1"},
+		{"default", "machine 10.0.0.1 login root password pass1\ndefault login root password pass2", "10.0.0.48", "pass2"},
+		{"machine before default", "default login root password pass2\nmachine 10.0.0.48 login root password pass1", "10.0.0.48", "pass1"},
+		{"quoted", `machine 10.0.0.48 login root password "pass word \"1\""`, "10.0.0.48", `pass word "1"`},
+		{"escaped", `machine 10.0.0.48 login root password pass\ word`, "10.0.0.48", "pass word"},
+		{"macdef", "macdef init\ncd /pub\nmachine 10.0.0.48 password wrong\n\nmachine 10.0.0.48 login root password pass1", "10.0.0.48", "pass1"},
+		{"macdef after machine", "machine 10.0.0.48 login root password pass1\nmacdef init\ncd /pub\n\ndefault password pass2", "10.0.0.1", "pass2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".netrc")
+			if err := os.WriteFile(path, []byte(tt.netrc), 0600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("NETRC", path)
+			got, err := netrcPassword(tt.machine)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("netrcPassword(%q) = %q, want %q", tt.machine, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetrcPasswordMissingFile(t *testing.T) {
+	t.Setenv("NETRC", filepath.Join(t.TempDir(), "missing"))
+	if got, err := netrcPassword("10.0.0.48"); got != "" || err != nil {
+		t.Errorf("netrcPassword() = %q, %v, want no password and no error", got, err)
+	}
+}
+
+func TestNetrcPasswordUnreadable(t *testing.T) {
+	// A directory can not be read as a file
+	t.Setenv("NETRC", t.TempDir())
+	if _, err := netrcPassword("10.0.0.48"); err == nil {
+		t.Error("netrcPassword() succeeded for an unreadable netrc file")
+	}
+}
+
+func TestNetrcTokens(t *testing.T) {
+	got := netrcTokens("machine a\tlogin \"\" password \"x y\"\r\nmacdef m\nbody\n\ndefault")
+	want := []string{"machine", "a", "login", "", "password", "x y", "default"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("netrcTokens() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+// events receives the -output json stream, it is nil for the human output.
+var events *eventWriter
+
+// setOutputFormat switches to the given -output format and returns the
+// writer of the human output. In json mode stdout only carries events, the
+// human output goes to stderr.
+func setOutputFormat(format string) (io.Writer, error) {
+	switch format {
+	case "human":
+		return os.Stdout, nil
+	case "json":
+		events = &eventWriter{out: os.Stdout}
+		return os.Stderr, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, use 'human' or 'json'", format)
+}
+
+// eventSink returns the event sink for builders, nil for the human output.
+func eventSink() builder.EventSink {
+	if events == nil {
+		return nil
+	}
+	return events
+}
+
+// eventWriter writes events as newline-delimited JSON. Emit does nothing on
+// a nil eventWriter.
+type eventWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (w *eventWriter) Emit(e builder.Event) {
+	if w == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.out.Write(append(data, '\n'))
+}
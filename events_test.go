@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSetOutputFormat(t *testing.T) {
+	stdout := os.Stdout
+	t.Cleanup(func() { events = nil })
+
+	out, err := setOutputFormat("human")
+	if err != nil || out != os.Stdout || events != nil {
+		t.Errorf("human: out %v, events %v, err %v", out, events, err)
+	}
+	out, err = setOutputFormat("json")
+	if err != nil || out != os.Stderr || events == nil || events.out != stdout {
+		t.Errorf("json: out %v, events %v, err %v", out, events, err)
+	}
+	if os.Stdout != stdout {
+		t.Error("json replaced os.Stdout")
+	}
+	if _, err := setOutputFormat("xml"); err == nil {
+		t.Error("xml: no error")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+// generateCommands are the subcommands of 'generate'.
+var generateCommands []*command
+
+func init() {
+	generateCommands = []*command{
+		{"generate params", "Generate typed Go accessors for the paramConfig of the manifest.", runGenerateParams},
+	}
+}
+
+func runGenerate(fs *flag.FlagSet, args []string) {
+	runSubcommand("generate", generateCommands, args)
+}
+
+func runGenerateParams(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	var out, pkg string
+	o.addAppFlags(fs)
+	fs.StringVar(&out, "o", builder.ParamsFile, "The file to write, relative to the application directory. Builds regenerate it.")
+	fs.StringVar(&pkg, "package", "", "The package of the file. (blank = the package of the application directory)")
+	o.parse(fs, args)
+
+	manifest := o.loadManifest()
+	manifestPath := filepath.Join(o.appDirectory, o.manifestPath)
+	dir := o.appDirectory
+	if dir == "" {
+		dir = "."
+	}
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(dir, out)
+	}
+	if pkg == "" {
+		var err error
+		if pkg, err = builder.PackageName(dir); err != nil {
+			handleError("Failed to read the package name", err)
+		}
+	}
+
+	src, err := builder.GenerateParams(manifest, pkg)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to generate parameters from %s", manifestPath), err)
+	}
+	if _, err := builder.WriteParams(out, src); err != nil {
+		handleError("Failed to write "+out, err)
+	}
+	fmt.Printf("Generated %s%s%s from %s, builds regenerate it\n", Green, out, Reset, manifestPath)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateParamsProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		projectConfigFile: "manifest: manifest.custom.json\n",
+		"main.go":         "package app\n",
+		"manifest.custom.json": `{
+  "schemaVersion": "1.7.0",
+  "acapPackageConf": {
+    "setup": {
+      "appName": "testapp",
+      "vendor": "Acme",
+      "runMode": "respawn",
+      "version": "1.2.3"
+    },
+    "configuration": {
+      "paramConfig": [{"name": "Threshold", "type": "int", "default": "50"}]
+    }
+  }
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, cmd := range generateCommands {
+		if cmd.name == "generate params" {
+			cmd.run(newFlagSet(cmd), []string{"-appdir", dir, "-o", "custom_gen.go"})
+		}
+	}
+
+	src, err := os.ReadFile(filepath.Join(dir, "custom_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(src), "// Code generated") || !strings.Contains(string(src), "package app\n") || !strings.Contains(string(src), `ParamThreshold = "Threshold"`) {
+		t.Errorf("generated from the wrong manifest or package:\n%s", src)
+	}
+}
@@ -6,6 +6,12 @@ require (
 	github.com/Cacsjep/goxis v0.0.0-20240416153132-42caf96f4615
 	github.com/Snawoot/go-http-digest-auth-client v1.1.3
 	github.com/docker/docker v26.0.0+incompatible
+	github.com/erikgeiser/promptkit v0.9.0
+	github.com/icholy/digest v1.1.0
+	github.com/moby/patternmatcher v0.6.0
+	golang.org/x/mod v0.13.0
+	golang.org/x/term v0.11.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -21,12 +27,10 @@ require (
 	github.com/distribution/reference v0.5.0 // indirect
 	github.com/docker/go-connections v0.5.0 // indirect
 	github.com/docker/go-units v0.5.0 // indirect
-	github.com/erikgeiser/promptkit v0.9.0 // indirect
 	github.com/felixge/httpsnoop v1.0.4 // indirect
 	github.com/go-logr/logr v1.4.1 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
-	github.com/icholy/digest v1.1.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-localereader v0.0.1 // indirect
@@ -48,10 +52,8 @@ require (
 	go.opentelemetry.io/otel/metric v1.24.0 // indirect
 	go.opentelemetry.io/otel/sdk v1.19.0 // indirect
 	go.opentelemetry.io/otel/trace v1.24.0 // indirect
-	golang.org/x/mod v0.13.0 // indirect
 	golang.org/x/sync v0.4.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
-	golang.org/x/term v0.11.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/time v0.0.0-20220210224613-90d013bbcef8 // indirect
 	golang.org/x/tools v0.14.0 // indirect
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/erikgeiser/promptkit/selection"
+)
+
+// licenseTexts are the licenses 'new' writes as LICENSE, one text/template
+// of licenseData per SPDX id.
+//
+//go:embed licenses
+var licenseTexts embed.FS
+
+// defaultLicense is the license of 'new' without -license and a terminal.
+const defaultLicense = "proprietary"
+
+// licenses are the choices of 'new -license', in the order they are offered.
+var licenses = []struct{ id, name string }{
+	{"MIT", "MIT License"},
+	{"Apache-2.0", "Apache License 2.0"},
+	{"BSD-2-Clause", "BSD 2-Clause \"Simplified\" License"},
+	{"BSD-3-Clause", "BSD 3-Clause \"New\" or \"Revised\" License"},
+	{"ISC", "ISC License"},
+	{"proprietary", "All rights reserved, not open source"},
+}
+
+// licenseData is what the license texts are rendered with.
+type licenseData struct {
+	Year   int
+	Holder string
+}
+
+// licenseIDs returns the ids of the built-in licenses.
+func licenseIDs() []string {
+	ids := make([]string, len(licenses))
+	for i, l := range licenses {
+		ids[i] = l.id
+	}
+	return ids
+}
+
+// licenseText returns the LICENSE of a new project: the built-in license with
+// the SPDX id license for holder, or else the content of the file license.
+func licenseText(license, holder string) ([]byte, error) {
+	for _, l := range licenses {
+		if !strings.EqualFold(l.id, license) {
+			continue
+		}
+		content, err := fs.ReadFile(licenseTexts, "licenses/"+l.id+".txt")
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(l.id).Parse(string(content))
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, licenseData{Year: time.Now().Year(), Holder: holder})
+		return buf.Bytes(), err
+	}
+	content, err := os.ReadFile(license)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%q is neither a file nor one of %s", license, strings.Join(licenseIDs(), ", "))
+	}
+	return content, err
+}
+
+// selectLicense asks for the license of a new project.
+func selectLicense() string {
+	choices := make([]string, len(licenses))
+	for i, l := range licenses {
+		choices[i] = fmt.Sprintf("%-12s %s", l.id, l.name)
+	}
+	sel := selection.New("License", choices)
+	choice, err := sel.RunPrompt()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return strings.Fields(choice)[0]
+}
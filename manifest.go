@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+// manifestCommands are the subcommands of 'manifest'.
+var manifestCommands []*command
+
+func init() {
+	manifestCommands = []*command{
+		{"manifest set", "Set manifest fields: manifest set <path>=<value>...", runManifestSet},
+		{"manifest param", "Add or remove a parameter: manifest param add|remove <name>", runManifestParam},
+		{"manifest version", "Bump the application version: manifest version bump major|minor|patch", runManifestVersion},
+		{"manifest migrate", "Rewrite the manifest for another schema version or AXIS OS version.", runManifestMigrate},
+	}
+}
+
+var appVersionRegex = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+
+func runManifest(fs *flag.FlagSet, args []string) {
+	runSubcommand("manifest", manifestCommands, args)
+}
+
+// runSubcommand runs the subcommand of parent named by args[0].
+func runSubcommand(parent string, cmds []*command, args []string) {
+	if len(args) > 0 {
+		for _, cmd := range cmds {
+			if cmd.name == parent+" "+args[0] {
+				cmd.run(newFlagSet(cmd), args[1:])
+				return
+			}
+		}
+	}
+	fmt.Printf("Usage: goxisbuilder %s <subcommand> [args] [flags]\n", parent)
+	fmt.Println("\nSubcommands:")
+	for _, cmd := range cmds {
+		fmt.Printf("  %-18s %s\n", cmd.name, cmd.summary)
+	}
+	os.Exit(1)
+}
+
+// loadManifestDoc loads the manifest selected by -appdir and -manifest.
+func (o *options) loadManifestDoc() *manifestDoc {
+	manifestPathFull := path.Join(o.appDirectory, o.manifestPath)
+	doc, err := loadManifestDoc(manifestPathFull)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to load manifest from %s", manifestPathFull), err)
+	}
+	return doc
+}
+
+// saveManifestDoc writes doc back to its file.
+func saveManifestDoc(doc *manifestDoc) {
+	if err := doc.save(""); err != nil {
+		handleError("Failed to write manifest", err)
+	}
+}
+
+func runManifestSet(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	var asJSON bool
+	o.addAppFlags(fs)
+	fs.BoolVar(&asJSON, "json", false, "Parse the values as JSON instead of strings, e.g. for numbers, booleans and objects.")
+	_, assignments := o.parseArgs(fs, args)
+	if len(assignments) == 0 {
+		fmt.Println("Usage: goxisbuilder manifest set [flags] <path>=<value>...")
+		os.Exit(1)
+	}
+
+	doc := o.loadManifestDoc()
+	for _, arg := range assignments {
+		key, raw, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			handleError("Invalid assignment", fmt.Errorf("%q, expected <path>=<value>", arg))
+		}
+		var value interface{} = raw
+		if asJSON {
+			if !json.Valid([]byte(raw)) {
+				handleError(fmt.Sprintf("Invalid JSON value for %s", key), fmt.Errorf("%s", raw))
+			}
+			v, err := builder.ParseJSON([]byte(raw))
+			if err != nil {
+				handleError(fmt.Sprintf("Invalid JSON value for %s", key), err)
+			}
+			value = v
+		}
+		if err := doc.Root.SetPath(key, value); err != nil {
+			handleError(fmt.Sprintf("Failed to set %s", key), err)
+		}
+		fmt.Printf("Set %s%s%s to %s\n", Blue, key, Reset, raw)
+	}
+	saveManifestDoc(doc)
+}
+
+func runManifestParam(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	var paramType, paramDefault string
+	o.addAppFlags(fs)
+	fs.StringVar(&paramType, "type", "string", "The parameter type for add, e.g. int:min=0;max=100, bool:no,yes or enum:a|b.")
+	fs.StringVar(&paramDefault, "default", "", "The default value for add.")
+	_, rest := o.parseArgs(fs, args)
+	if len(rest) != 2 || (rest[0] != "add" && rest[0] != "remove") {
+		fmt.Println("Usage: goxisbuilder manifest param add|remove [flags] <name>")
+		os.Exit(1)
+	}
+	action, name := rest[0], rest[1]
+
+	doc := o.loadManifestDoc()
+	conf := doc.Configuration()
+	current, _ := conf.Get("paramConfig")
+	params, _ := current.([]interface{})
+	index := -1
+	for i, item := range params {
+		if param, ok := item.(*builder.JSONObject); ok {
+			if n, _ := param.Get("name"); n == name {
+				index = i
+			}
+		}
+	}
+
+	if action == "add" {
+		if index >= 0 {
+			handleError("Failed to add parameter", fmt.Errorf("%s already exists", name))
+		}
+		param := builder.NewJSONObject()
+		param.Set("name", name)
+		param.Set("default", paramDefault)
+		param.Set("type", paramType)
+		conf.Set("paramConfig", append(params, param))
+		saveManifestDoc(doc)
+		fmt.Printf("Added parameter %s%s%s (%s, default %q)\n", Blue, name, Reset, paramType, paramDefault)
+		return
+	}
+
+	if index < 0 {
+		handleError("Failed to remove parameter", fmt.Errorf("%s does not exist", name))
+	}
+	if params = append(params[:index], params[index+1:]...); len(params) == 0 {
+		conf.Remove("paramConfig")
+	} else {
+		conf.Set("paramConfig", params)
+	}
+	saveManifestDoc(doc)
+	fmt.Printf("Removed parameter %s%s%s\n", Blue, name, Reset)
+}
+
+func runManifestVersion(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	o.addAppFlags(fs)
+	_, rest := o.parseArgs(fs, args)
+	if len(rest) != 2 || rest[0] != "bump" {
+		fmt.Println("Usage: goxisbuilder manifest version bump major|minor|patch [flags]")
+		os.Exit(1)
+	}
+
+	doc := o.loadManifestDoc()
+	setup := doc.Setup()
+	current, _ := setup.Get("version")
+	version, _ := current.(string)
+	next, err := bumpVersion(version, rest[1])
+	if err != nil {
+		handleError("Failed to bump version", err)
+	}
+	setup.Set("version", next)
+	saveManifestDoc(doc)
+	fmt.Printf("Version %s -> %s%s%s\n", version, Green, next, Reset)
+}
+
+// bumpVersion increments the major, minor or patch part of version and
+// resets the parts after it. Pre-release and build suffixes are dropped.
+func bumpVersion(version, part string) (string, error) {
+	m := appVersionRegex.FindStringSubmatch(version)
+	if m == nil {
+		return "", fmt.Errorf("%q is not a version like 1.2.3", version)
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+	switch part {
+	case "major":
+		major, minor, patch = major+1, 0, 0
+	case "minor":
+		minor, patch = minor+1, 0
+	case "patch":
+		patch++
+	default:
+		return "", fmt.Errorf("unknown part %q, expected major, minor or patch", part)
+	}
+	return fmt.Sprintf("%d.%d.%d", major, minor, patch), nil
+}
+
+func runManifestMigrate(fs *flag.FlagSet, args []string) {
+	o := &options{}
+	var to, firmware, out string
+	o.addAppFlags(fs)
+	fs.StringVar(&to, "to", "", "The schema version to migrate to, e.g. 1.7.0.")
+	fs.StringVar(&firmware, "firmware", "", "Migrate to the newest schema this AXIS OS version supports, instead of -to.")
+	fs.StringVar(&out, "o", "", "Write the migrated manifest to this file instead of replacing the manifest, '-' for stdout.")
+	fs.StringVar(&o.compatFile, "compat", "", "A compatibility file merged into the built-in data.")
+	o.parse(fs, args)
+
+	db := o.compatDB()
+	switch {
+	case (to == "") == (firmware == ""):
+		fmt.Println("Pass either -to <schema> or -firmware <AXIS OS version>.")
+		os.Exit(1)
+	case firmware != "":
+		rec, err := db.ForFirmware(firmware)
+		if err != nil {
+			handleError("Unsupported firmware", err)
+		}
+		to = rec.Schema.Version
+	case !builder.ValidSchemaVersion(to):
+		handleError("Invalid schema version", fmt.Errorf("%q, expected e.g. 1.7.0", to))
+	}
+	if _, ok := db.Schema(to); !ok {
+		fmt.Printf("Schema %s is not in the compatibility data, only schemaVersion is changed.\n", to)
+	}
+
+	doc := o.loadManifestDoc()
+	from := doc.SchemaVersion()
+	notes := migrateManifest(doc, db, to)
+	if out != "" && out != "-" && o.appDirectory != "" && !path.IsAbs(out) {
+		out = path.Join(o.appDirectory, out)
+	}
+	if err := doc.save(out); err != nil {
+		handleError("Failed to write manifest", err)
+	}
+	if out == "-" {
+		return
+	}
+	if out == "" {
+		out = doc.path
+	}
+	for _, note := range notes {
+		fmt.Println("    ", note)
+	}
+	fmt.Printf("Migrated %s from schema %s to %s, written to %s\n", doc.path, from, to, out)
+}
+
+// migrateManifest sets the schemaVersion of doc to to. Migrating down drops
+// the fields of the schemas above to and converts what the older schema
+// requires, newer schemas only add fields. It returns what was changed.
+func migrateManifest(doc *manifestDoc, db *builder.CompatDB, to string) []string {
+	from := doc.SchemaVersion()
+	var notes []string
+	if builder.CompareVersions(to, from) < 0 {
+		for _, schema := range db.Schemas {
+			if builder.CompareVersions(schema.Version, to) <= 0 || builder.CompareVersions(schema.Version, from) > 0 {
+				continue
+			}
+			for _, field := range schema.Fields {
+				if doc.Root.RemovePath(field) {
+					notes = append(notes, fmt.Sprintf("Dropped %s, it needs schema %s", field, schema.Version))
+				}
+			}
+		}
+
+		setup := doc.Setup()
+		// Without resources.linux the application needs a static user
+		if _, ok := setup.Get("user"); !ok && builder.CompareVersions(to, "1.5.0") < 0 {
+			user := builder.NewJSONObject()
+			user.Set("username", "sdk")
+			user.Set("group", "sdk")
+			setup.Set("user", user)
+			notes = append(notes, "Added acapPackageConf.setup.user sdk:sdk, schemas before 1.5.0 need a static user")
+		}
+		// embeddedSdkVersion is required before schema 1.3
+		if _, ok := setup.Get("embeddedSdkVersion"); !ok && builder.CompareVersions(to, "1.3") < 0 {
+			setup.Set("embeddedSdkVersion", "3.0")
+			notes = append(notes, "Added acapPackageConf.setup.embeddedSdkVersion 3.0, schemas before 1.3 need it")
+		}
+		if resources := doc.Root.Object("resources", false); resources != nil && len(resources.Keys()) == 0 {
+			doc.Root.Remove("resources")
+		}
+	}
+	doc.Root.Set("schemaVersion", to)
+	return notes
+}
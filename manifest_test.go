@@ -0,0 +1,232 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+const migrateManifestJSON = `{
+    "schemaVersion": "1.8.0",
+    "resources": {
+        "dbus": {
+            "requiredMethods": ["com.axis.HTTPConf1.VAPIXServiceAccounts1.GetCredentials"]
+        },
+        "linux": {
+            "user": {
+                "groups": ["storage"]
+            }
+        }
+    },
+    "acapPackageConf": {
+        "setup": {
+            "appName": "testapp",
+            "vendor": "Acme",
+            "runMode": "respawn",
+            "version": "1.2.3"
+        },
+        "configuration": {
+            "reverseProxy": [
+                {"apiPath": "api", "target": "http://localhost:2001", "access": "admin"}
+            ]
+        }
+    }
+}
+`
+
+// testManifestDoc parses a manifest for the tests.
+func testManifestDoc(t *testing.T, data string) *manifestDoc {
+	t.Helper()
+	doc, err := builder.ParseManifestDoc([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &manifestDoc{ManifestDoc: doc, path: "manifest.json"}
+}
+
+func TestMigrateManifest(t *testing.T) {
+	tests := []struct {
+		name, from, to string
+		wantDropped    []string
+		wantUser       bool
+		wantEmbedded   bool
+	}{
+		{name: "keeps the fields of older schemas", from: "1.8.0", to: "1.7.0"},
+		{name: "drops reverseProxy", from: "1.8.0", to: "1.6.0", wantDropped: []string{"acapPackageConf.configuration.reverseProxy"}},
+		{
+			name: "adds a static user", from: "1.8.0", to: "1.4.0",
+			wantDropped: []string{"resources.linux", "acapPackageConf.configuration.reverseProxy"}, wantUser: true,
+		},
+		{
+			name: "adds embeddedSdkVersion", from: "1.8.0", to: "1.2",
+			wantDropped: []string{"resources.dbus", "resources.linux", "acapPackageConf.configuration.reverseProxy"}, wantUser: true, wantEmbedded: true,
+		},
+		{name: "migrating up", from: "1.2", to: "1.8.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := testManifestDoc(t, strings.Replace(migrateManifestJSON, `"1.8.0"`, `"`+tt.from+`"`, 1))
+			notes := migrateManifest(doc, builder.DefaultCompatDB(), tt.to)
+
+			if got := doc.SchemaVersion(); got != tt.to {
+				t.Errorf("schemaVersion = %s, want %s", got, tt.to)
+			}
+			var dropped []string
+			for _, note := range notes {
+				if field, ok := strings.CutPrefix(note, "Dropped "); ok {
+					field, _, _ = strings.Cut(field, ",")
+					dropped = append(dropped, field)
+				}
+			}
+			if !reflect.DeepEqual(dropped, tt.wantDropped) {
+				t.Errorf("dropped %v, want %v", dropped, tt.wantDropped)
+			}
+			data, err := doc.Bytes()
+			if err != nil {
+				t.Fatalf("migrated manifest is invalid: %v", err)
+			}
+			for _, field := range tt.wantDropped {
+				if key := field[strings.LastIndex(field, ".")+1:]; strings.Contains(string(data), `"`+key+`"`) {
+					t.Errorf("%s is still in the manifest", field)
+				}
+			}
+			user, hasUser := doc.Setup().Get("user")
+			if hasUser != tt.wantUser {
+				t.Errorf("setup.user = %v, want it %v", user, tt.wantUser)
+			}
+			if _, ok := doc.Setup().Get("embeddedSdkVersion"); ok != tt.wantEmbedded {
+				t.Errorf("setup.embeddedSdkVersion present %v, want %v", ok, tt.wantEmbedded)
+			}
+		})
+	}
+}
+
+func TestMigrateManifestOutput(t *testing.T) {
+	doc := testManifestDoc(t, migrateManifestJSON)
+	migrateManifest(doc, builder.DefaultCompatDB(), "1.4.0")
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key order and indentation are kept, resources is dropped once empty
+	want := `{
+    "schemaVersion": "1.4.0",
+    "resources": {
+        "dbus": {
+            "requiredMethods": [
+                "com.axis.HTTPConf1.VAPIXServiceAccounts1.GetCredentials"
+            ]
+        }
+    },
+    "acapPackageConf": {
+        "setup": {
+            "appName": "testapp",
+            "vendor": "Acme",
+            "runMode": "respawn",
+            "version": "1.2.3",
+            "user": {
+                "username": "sdk",
+                "group": "sdk"
+            }
+        },
+        "configuration": {}
+    }
+}
+`
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestMigrateManifestKeepsUser(t *testing.T) {
+	doc := testManifestDoc(t, strings.Replace(migrateManifestJSON, `"version": "1.2.3"`, `"version": "1.2.3", "user": {"username": "app", "group": "app"}, "embeddedSdkVersion": "2.0"`, 1))
+	notes := migrateManifest(doc, builder.DefaultCompatDB(), "1.2")
+	for _, note := range notes {
+		if strings.HasPrefix(note, "Added") {
+			t.Errorf("unexpected change: %s", note)
+		}
+	}
+	if v, _ := doc.Setup().Get("embeddedSdkVersion"); v != "2.0" {
+		t.Errorf("embeddedSdkVersion = %v, want 2.0", v)
+	}
+}
+
+func TestBumpVersion(t *testing.T) {
+	tests := []struct {
+		version, part, want, wantErr string
+	}{
+		{version: "1.2.3", part: "patch", want: "1.2.4"},
+		{version: "1.2.3", part: "minor", want: "1.3.0"},
+		{version: "1.2.3", part: "major", want: "2.0.0"},
+		{version: "0.9.9", part: "minor", want: "0.10.0"},
+		{version: "1.2.3-rc.1", part: "patch", want: "1.2.4"},
+		{version: "1.2.3+build.5", part: "major", want: "2.0.0"},
+		{version: "1.2", part: "patch", wantErr: `"1.2" is not a version like 1.2.3`},
+		{version: "", part: "patch", wantErr: "is not a version"},
+		{version: "1.2.3", part: "build", wantErr: `unknown part "build"`},
+	}
+	for _, tt := range tests {
+		got, err := bumpVersion(tt.version, tt.part)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("bumpVersion(%q, %q) = %q, %v, want error %q", tt.version, tt.part, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("bumpVersion(%q, %q) = %q, %v, want %q", tt.version, tt.part, got, err, tt.want)
+		}
+	}
+}
+
+// TestManifestParamProjectConfig adds a parameter with the flags after its
+// name to the manifest selected in goxis.yaml.
+func TestManifestParamProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		projectConfigFile: "manifest: manifest.custom.json\n",
+		"manifest.custom.json": `{
+  "schemaVersion": "1.7.0",
+  "acapPackageConf": {
+    "setup": {
+      "appName": "testapp",
+      "vendor": "Acme",
+      "runMode": "respawn",
+      "version": "1.2.3"
+    }
+  }
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, cmd := range manifestCommands {
+		if cmd.name == "manifest param" {
+			cmd.run(newFlagSet(cmd), []string{"add", "Threshold", "-type", "int:min=0;max=100", "-default", "50", "-appdir", dir})
+		}
+	}
+
+	doc, err := loadManifestDoc(filepath.Join(dir, "manifest.custom.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, _ := doc.Configuration().Get("paramConfig")
+	items, _ := params.([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("paramConfig = %v, want one parameter", params)
+	}
+	param := items[0].(*builder.JSONObject)
+	for key, want := range map[string]string{"name": "Threshold", "type": "int:min=0;max=100", "default": "50"} {
+		if got, _ := param.Get(key); got != want {
+			t.Errorf("%s = %v, want %s", key, got, want)
+		}
+	}
+}
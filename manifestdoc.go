@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+// manifestDoc is a manifest.json file loaded for editing.
+type manifestDoc struct {
+	*builder.ManifestDoc
+	path string
+}
+
+// loadManifestDoc loads the manifest at path, which also has to load with
+// axmanifest.LoadManifest.
+func loadManifestDoc(path string) (*manifestDoc, error) {
+	if _, err := axmanifest.LoadManifest(path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := builder.ParseManifestDoc(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &manifestDoc{ManifestDoc: doc, path: path}, nil
+}
+
+// save writes the manifest to path, or to the file it was loaded from if
+// path is blank, "-" writes it to stdout. The result has to load with
+// axmanifest.LoadManifest.
+func (d *manifestDoc) save(path string) error {
+	if path == "" {
+		path = d.path
+	}
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifestDocRoundTrip(t *testing.T) {
+	const manifest = `{
+    "schemaVersion": "1.7.0",
+    "acapPackageConf": {
+        "setup": {
+            "version": "1.2.3",
+            "appName": "testapp",
+            "vendor": "Acme & Sons <acme.example>",
+            "runMode": "respawn"
+        },
+        "configuration": {
+            "paramConfig": [
+                {
+                    "name": "Scale",
+                    "default": "1.50",
+                    "type": "float"
+                }
+            ],
+            "settingPage": "index.html"
+        }
+    }
+}`
+	tests := []struct {
+		name, data string
+	}{
+		{"four spaces and newline", manifest + "\n"},
+		{"two spaces", strings.ReplaceAll(manifest, "    ", "  ") + "\n"},
+		{"tabs", strings.ReplaceAll(manifest, "    ", "\t") + "\n"},
+		{"no trailing newline", manifest},
+		{"CRLF trailing newline", manifest + "\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "manifest.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			doc, err := loadManifestDoc(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := doc.save(""); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.data {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.data)
+			}
+
+			// New keys are appended, existing ones keep their place
+			doc.Setup().Set("friendlyName", "Test")
+			doc.Setup().Set("version", "1.3.0")
+			out := filepath.Join(filepath.Dir(path), "edited.json")
+			if err := doc.save(out); err != nil {
+				t.Fatal(err)
+			}
+			edited, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := string(edited)
+			if !(strings.Index(s, `"version": "1.3.0"`) < strings.Index(s, `"appName"`) && strings.Index(s, `"runMode"`) < strings.Index(s, `"friendlyName": "Test"`)) {
+				t.Errorf("unexpected key order:\n%s", s)
+			}
+		})
+	}
+}
+
+func TestLoadManifestDocInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"trailing.json": `{"schemaVersion": "1.7.0", "acapPackageConf": {"setup": {"appName": "a", "vendor": "v", "runMode": "never", "version": "1.0.0"}}} {}`,
+		"broken.json":   `{"schemaVersion": `,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadManifestDoc(path); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+	"github.com/docker/docker/client"
+)
+
+// buildResult is the outcome of one build of a multi build run.
+type buildResult struct {
+	Config    *builder.BuildConfiguration
+	Artifacts []builder.Artifact
+	LogPath   string
+	Err       error
+	Duration  time.Duration
+}
+
+// buildFunc runs the build of one configuration.
+type buildFunc func(ctx context.Context, bc *builder.BuildConfiguration) (*builder.Result, error)
+
+// buildAll runs one build per configuration, at most jobs at once. The output
+// of every build is written to a log file in its output directory and to
+// out, each line prefixed with the build name, or sent as log events
+// with -output json. Results are in the order of configs.
+func buildAll(ctx context.Context, cli *client.Client, configs []*builder.BuildConfiguration, jobs int, out io.Writer) []buildResult {
+	return runBuilds(ctx, configs, jobs, out, func(ctx context.Context, bc *builder.BuildConfiguration) (*builder.Result, error) {
+		return builder.New(cli, bc, eventSink()).Build(ctx)
+	})
+}
+
+// runBuilds is buildAll with the builds run by build.
+func runBuilds(ctx context.Context, configs []*builder.BuildConfiguration, jobs int, out io.Writer, build buildFunc) []buildResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+	slots := make(chan struct{}, jobs)
+
+	results := make([]buildResult, len(configs))
+	var outMu sync.Mutex
+	var wg sync.WaitGroup
+	for i, bc := range configs {
+		wg.Add(1)
+		go func(i int, bc *builder.BuildConfiguration) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			name := bc.Name()
+			result := buildResult{
+				Config:  bc,
+				LogPath: filepath.Join(bc.OutputDir, name+".log"),
+			}
+
+			if err := os.MkdirAll(bc.OutputDir, os.FileMode(0755)); err != nil {
+				result.Err = fmt.Errorf("failed to create build directory: %w", err)
+				results[i] = result
+				return
+			}
+			logFile, err := os.Create(result.LogPath)
+			if err != nil {
+				result.Err = fmt.Errorf("failed to create log file: %w", err)
+				results[i] = result
+				return
+			}
+			defer logFile.Close()
+
+			bc.Log = logFile
+			if events == nil {
+				prefixed := &prefixWriter{mu: &outMu, out: out, prefix: "[" + name + "] "}
+				defer prefixed.Flush()
+				bc.Log = io.MultiWriter(prefixed, logFile)
+			}
+
+			start := time.Now()
+			built, err := build(ctx, bc)
+			result.Duration = time.Since(start)
+			if err != nil {
+				result.Err = err
+				fmt.Fprintf(bc.Log, "Build failed: %v\n", err)
+			} else {
+				result.Artifacts = built.Artifacts
+			}
+			results[i] = result
+		}(i, bc)
+	}
+	wg.Wait()
+	return results
+}
+
+// printBuildSummary prints a table of all builds to out and reports whether all succeeded.
+func printBuildSummary(out io.Writer, results []buildResult) bool {
+	passed := 0
+	fmt.Fprintln(out, "\n\nBuild summary:")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BUILD\tIMAGE\tSDK\tMANIFEST\tSTATUS\tDURATION\tEAP\tLOG")
+	for _, r := range results {
+		status := "FAILED"
+		if r.Err == nil {
+			status = "OK"
+			passed++
+		}
+		eap := "-"
+		if len(r.Artifacts) > 0 {
+			eap = r.Artifacts[0].Path
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Config.Name(), r.Config.ImageName, r.Config.Version, r.Config.ManifestPath, status, r.Duration.Round(time.Second), eap, r.LogPath)
+	}
+	tw.Flush()
+	fmt.Fprintf(out, "%d of %d builds passed\n", passed, len(results))
+	events.Emit(builder.Event{Type: builder.EventSummary, Summary: &builder.BuildSummary{Passed: passed, Total: len(results)}})
+	return passed == len(results)
+}
+
+// prefixWriter writes complete lines to out, each prefixed with prefix.
+// prefixWriters sharing mu never interleave within a line.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a trailing line that has no newline yet.
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+}
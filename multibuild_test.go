@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &prefixWriter{mu: &sync.Mutex{}, out: &out, prefix: "[a] "}
+	for _, p := range []string{"hel", "lo\nwor", "ld\n\n", "tail"} {
+		if n, err := w.Write([]byte(p)); n != len(p) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", p, n, err)
+		}
+	}
+	if got, want := out.String(), "[a] hello\n[a] world\n[a] \n"; got != want {
+		t.Errorf("before Flush %q, want %q", got, want)
+	}
+	w.Flush()
+	w.Flush()
+	if got, want := out.String(), "[a] hello\n[a] world\n[a] \n[a] tail\n"; got != want {
+		t.Errorf("after Flush %q, want %q", got, want)
+	}
+}
+
+func TestPrefixWriterConcurrent(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := &prefixWriter{mu: &mu, out: &out, prefix: fmt.Sprintf("[%d] ", i)}
+			for j := 0; j < 50; j++ {
+				// Byte by byte, so every line spans many writes
+				for _, b := range []byte(fmt.Sprintf("line %d of %d\n", j, i)) {
+					w.Write([]byte{b})
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 200 {
+		t.Fatalf("got %d lines, want 200", len(lines))
+	}
+	for _, line := range lines {
+		var i, j, k int
+		if n, _ := fmt.Sscanf(line, "[%d] line %d of %d", &i, &j, &k); n != 3 || i != k {
+			t.Errorf("interleaved line %q", line)
+		}
+	}
+}
+
+// testConfigs returns n build configurations with their output in dir.
+func testConfigs(dir string, n int) []*builder.BuildConfiguration {
+	configs := make([]*builder.BuildConfiguration, n)
+	for i := range configs {
+		configs[i] = &builder.BuildConfiguration{Arch: fmt.Sprintf("arch%d", i), OutputDir: filepath.Join(dir, "build")}
+	}
+	return configs
+}
+
+func TestRunBuilds(t *testing.T) {
+	dir := t.TempDir()
+	configs := testConfigs(dir, 6)
+	var running, maxRunning atomic.Int32
+	build := func(ctx context.Context, bc *builder.BuildConfiguration) (*builder.Result, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			max := maxRunning.Load()
+			if n <= max || maxRunning.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		fmt.Fprintf(bc.Log, "building %s\n", bc.Name())
+		time.Sleep(20 * time.Millisecond)
+		if bc.Arch == "arch3" {
+			return nil, errors.New("compile error")
+		}
+		return &builder.Result{Artifacts: []builder.Artifact{{Path: bc.Name() + ".eap"}}}, nil
+	}
+
+	var out bytes.Buffer
+	results := runBuilds(context.Background(), configs, 2, &out, build)
+	if max := maxRunning.Load(); max > 2 {
+		t.Errorf("%d builds ran at once, want at most 2", max)
+	}
+	if len(results) != len(configs) {
+		t.Fatalf("got %d results, want %d", len(results), len(configs))
+	}
+	for i, r := range results {
+		name := configs[i].Name()
+		if r.Config != configs[i] {
+			t.Errorf("result %d is of %s, want %s", i, r.Config.Name(), name)
+		}
+		if wantErr := name == "arch3"; (r.Err != nil) != wantErr || !wantErr && (len(r.Artifacts) != 1 || r.Artifacts[0].Path != name+".eap") {
+			t.Errorf("result of %s: %v, %v", name, r.Artifacts, r.Err)
+		}
+		log, err := os.ReadFile(r.LogPath)
+		if err != nil || !strings.HasPrefix(string(log), "building "+name+"\n") {
+			t.Errorf("log of %s: %q, %v", name, log, err)
+		}
+		if !strings.Contains(out.String(), "["+name+"] building "+name+"\n") {
+			t.Errorf("output has no prefixed line of %s:\n%s", name, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "[arch3] Build failed: compile error\n") {
+		t.Errorf("output does not report the failed build:\n%s", out.String())
+	}
+
+	// Without a valid job count the builds run one by one
+	maxRunning.Store(0)
+	runBuilds(context.Background(), testConfigs(dir, 3), 0, &out, build)
+	if max := maxRunning.Load(); max != 1 {
+		t.Errorf("%d builds ran at once with jobs 0, want 1", max)
+	}
+}
+
+func TestPrintBuildSummary(t *testing.T) {
+	ok := buildResult{Config: &builder.BuildConfiguration{Arch: "aarch64"}, Artifacts: []builder.Artifact{{Path: "build/app_aarch64.eap"}}}
+	failed := buildResult{Config: &builder.BuildConfiguration{Arch: "armv7hf"}, Err: errors.New("compile error")}
+
+	var out bytes.Buffer
+	if !printBuildSummary(&out, []buildResult{ok}) {
+		t.Error("one passed build is reported as failed")
+	}
+	out.Reset()
+	if printBuildSummary(&out, []buildResult{ok, failed}) {
+		t.Error("a failed build is reported as passed, the command would exit with 0")
+	}
+	for _, want := range []string{"build/app_aarch64.eap", "FAILED", "1 of 2 builds passed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("summary has no %q:\n%s", want, out.String())
+		}
+	}
+}
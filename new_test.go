@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// runGoxisbuilder runs the test binary as goxisbuilder
+	if os.Getenv("GOXISBUILDER_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runGoxisbuilder runs goxisbuilder with args in dir, without a terminal and
+// offline. It returns the output and whether it succeeded.
+func runGoxisbuilder(t *testing.T, dir string, args ...string) (string, bool) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GOXISBUILDER_TEST_MAIN=1",
+		"XDG_CONFIG_HOME="+t.TempDir(),
+		"GOPROXY=off",
+		"GOFLAGS=-mod=mod",
+		"GOSUMDB=off",
+	)
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		t.Fatal(err)
+	}
+	return string(out), err == nil
+}
+
+func TestNewNonInteractive(t *testing.T) {
+	goxisDir(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"new.yaml": `module: example.com/acme/counter
+appname: counter
+friendlyname: Counter
+vendor: Config Vendor
+template: params
+license: MIT
+`,
+	})
+
+	out, ok := runGoxisbuilder(t, dir, "new", "-config", "new.yaml", "-vendor", "Acme", "-dir", "counter")
+	if !ok {
+		t.Fatalf("new failed:\n%s", out)
+	}
+	if !strings.Contains(out, "Project created successfully from the params template") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	app := filepath.Join(dir, "counter")
+	for name, want := range map[string]string{
+		"manifest.json":   `"appName": "counter"`,
+		"LICENSE":         "Acme",
+		"go.mod":          "module example.com/acme/counter",
+		projectConfigFile: "sdk: ",
+		"params_gen.go":   "// Code generated",
+	} {
+		content, err := os.ReadFile(filepath.Join(app, name))
+		if err != nil || !strings.Contains(string(content), want) {
+			t.Errorf("%s does not contain %q: %v\n%s", name, want, err, content)
+		}
+	}
+	if manifest, _ := os.ReadFile(filepath.Join(app, "manifest.json")); !strings.Contains(string(manifest), `"vendor": "Acme"`) {
+		t.Errorf("-vendor does not override the config file:\n%s", manifest)
+	}
+	if goMod, _ := os.ReadFile(filepath.Join(app, "go.mod")); !strings.Contains(string(goMod), goxisModule+" "+goxisVersion()) {
+		t.Errorf("go.mod does not require goxis %s:\n%s", goxisVersion(), goMod)
+	}
+}
+
+func TestNewNonInteractiveErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"unknown.yaml": "module: example.com/app\ncolor: blue\n",
+		"pwd.yaml":     "pwd: secret\n",
+	})
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"missing values", []string{"-module", "example.com/app"}, "no terminal to ask for the missing values, pass -appname, -friendlyname, -vendor"},
+		{"invalid value", []string{"-module", "app", "-appname", "app", "-friendlyname", "App", "-vendor", "Acme"}, "-module: invalid Go module path format"},
+		{"unknown config key", []string{"-config", "unknown.yaml"}, `unknown.yaml: unknown key "color"`},
+		{"not a flag of new", []string{"-config", "pwd.yaml"}, `pwd.yaml: unknown key "pwd"`},
+		{"missing config", []string{"-config", "missing.yaml"}, "failed to read missing.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, ok := runGoxisbuilder(t, dir, append([]string{"new", "-dir", "app"}, tt.args...)...)
+			if ok || !strings.Contains(out, tt.want) {
+				t.Errorf("new %v succeeded %v, want %q:\n%s", tt.args, ok, tt.want, out)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "app")); err == nil {
+				t.Error("a failed new created the project directory")
+			}
+		})
+	}
+}
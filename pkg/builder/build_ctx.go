@@ -0,0 +1,316 @@
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// Embed your Dockerfile
+//
+//go:embed Dockerfile
+var embeddedFiles embed.FS
+
+// ignoreFileNames are the ignore files looked up in the root of the build
+// context and in the application directory, the first one found is used.
+var ignoreFileNames = []string{".goxisignore", ".dockerignore"}
+
+// defaultIgnorePatterns are excluded unless an ignore file re-includes them with '!'.
+var defaultIgnorePatterns = []string{".git", "**/.git", "build", "**/*.eap"}
+
+// contextEpoch is the modification time of every entry in the build context,
+// so unchanged sources always produce the same tarball and hit the Docker cache.
+var contextEpoch = time.Unix(0, 0)
+
+// contextEntry is a file or directory of the build context.
+type contextEntry struct {
+	name string // slash separated path inside the tarball
+	path string
+	info os.FileInfo
+}
+
+// createBuildContext streams the build context: the base directory without
+// ignored files, plus the embedded or custom Dockerfile, the Makefile of the
+// application, its selected manifest, the third-party notices, the
+// regenerated params files and the Go toolchain. Entries are sorted and
+// their headers normalized, so the same sources always give the same tarball.
+func createBuildContext(out io.Writer, baseDir string, bc *BuildConfiguration, info BuildInfo, tc goToolchain, notices []byte, params map[string][]byte) (io.ReadCloser, error) {
+	appDir := bc.AppDirectory
+	generated, err := generatedFiles(out, baseDir, bc, info)
+	if err != nil {
+		return nil, err
+	}
+	// The Dockerfile installs the archive of the toolchain directory, if any,
+	// instead of downloading Go
+	generated[toolchainDir+"/version"] = []byte(tc.Version + "\n")
+	if notices != nil {
+		generated[path.Join(appDir, NoticesFile)] = notices
+	}
+	for name, src := range params {
+		generated[name] = src
+	}
+
+	patterns, err := ignorePatterns(baseDir, appDir, bc.IgnoreDirs)
+	if err != nil {
+		return nil, err
+	}
+	pm, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignore pattern: %w", err)
+	}
+
+	var entries []contextEntry
+	err = filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip the root directory entry
+		if path == baseDir {
+			fmt.Fprintln(out, "Skipping base directory:", baseDir)
+			return nil
+		}
+
+		// Skip files or directories that start with an underscore
+		if strings.HasPrefix(filepath.Base(path), "_") {
+			if info.IsDir() {
+				return filepath.SkipDir // Skip entire directory if it starts with an underscore
+			}
+			return nil // Skip this file
+		}
+
+		// Never send credential files to the Docker daemon
+		if name := filepath.Base(path); name == ".netrc" || name == "_netrc" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return err
+		}
+
+		// The generated files and the toolchain are added below
+		if _, ok := generated[filepath.ToSlash(rel)]; ok {
+			return nil
+		}
+		if rel == toolchainDir {
+			return filepath.SkipDir
+		}
+
+		ignored, err := pm.MatchesOrParentMatches(rel)
+		if err != nil {
+			return err
+		}
+		if ignored {
+			// Without '!' patterns nothing below an ignored directory can be re-included
+			if info.IsDir() && !pm.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		entries = append(entries, contextEntry{name: filepath.ToSlash(rel), path: path, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error adding current directory to tar: %w", err)
+	}
+	if tc.Tarball != "" {
+		fi, err := os.Stat(tc.Tarball)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Go toolchain: %w", err)
+		}
+		entries = append(entries, contextEntry{name: toolchainDir + "/go.tar.gz", path: tc.Tarball, info: fi})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeBuildContext(pw, out, entries, generated))
+	}()
+	return pr, nil
+}
+
+// generatedFiles returns the Dockerfile, Makefile and manifest.json of the
+// build context by their path in the context.
+func generatedFiles(out io.Writer, baseDir string, bc *BuildConfiguration, info BuildInfo) (map[string][]byte, error) {
+	var dockerfileData []byte
+	var err error
+	if bc.Dockerfile == "" {
+		dockerfileData, err = fs.ReadFile(embeddedFiles, "Dockerfile")
+		if err != nil {
+			return nil, fmt.Errorf("error reading embedded Dockerfile: %w", err)
+		}
+	} else {
+		dockerfileData, err = os.ReadFile(bc.Dockerfile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading custom Dockerfile: %w", err)
+		}
+		fmt.Fprintln(out, "Using custom dockerfile: ", bc.Dockerfile)
+	}
+
+	files, command, err := buildRecipe(bc, info, func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(baseDir, filepath.FromSlash(name)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if bc.ManifestPath != "" && path.Clean(bc.ManifestPath) != defaultManifest {
+		fmt.Fprintf(out, "Using manifest %s as %s\n", bc.ManifestPath, defaultManifest)
+	}
+	if info.Version != bc.Manifest.ACAPPackageConf.Setup.Version {
+		fmt.Fprintf(out, "Stamped manifest version %s\n", info.Version)
+	}
+	if bc.SchemaVersion != "" && bc.SchemaVersion != bc.Manifest.SchemaVersion {
+		fmt.Fprintf(out, "Using manifest schema %s instead of %s\n", bc.SchemaVersion, bc.Manifest.SchemaVersion)
+	}
+	fmt.Fprintln(out, "Go build command:", command)
+
+	files["Dockerfile"] = dockerfileData
+	return files, nil
+}
+
+// writeBuildContext writes the tarball of the build context to w.
+func writeBuildContext(w io.Writer, out io.Writer, entries []contextEntry, generated map[string][]byte) error {
+	tw := tar.NewWriter(w)
+
+	for _, entry := range entries {
+		if err := writeContextEntry(tw, out, entry); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(generated))
+	for name := range generated {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data := generated[name]
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: contextEpoch,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	// Make sure to close the tar writer to flush buffers
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error finalizing tarball: %w", err)
+	}
+	return nil
+}
+
+// writeContextEntry writes one file, directory or symlink with a normalized header.
+func writeContextEntry(tw *tar.Writer, out io.Writer, entry contextEntry) error {
+	link := ""
+	if entry.info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(entry.path); err != nil {
+			return err
+		}
+	}
+	header, err := tar.FileInfoHeader(entry.info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = entry.name
+	header.ModTime = contextEpoch
+	header.AccessTime = time.Time{}
+	header.ChangeTime = time.Time{}
+	header.Uid, header.Gid = 0, 0
+	header.Uname, header.Gname = "", ""
+	header.PAXRecords = nil
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !entry.info.Mode().IsRegular() {
+		return nil
+	}
+
+	// Write file content
+	fmt.Fprintln(out, "Copy file into docker ctx:", entry.name)
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// ignorePatterns collects the dockerignore style patterns, relative to
+// baseDir, in increasing priority: defaults, -ignore directories, the ignore
+// file of baseDir and the ignore file of the application directory.
+func ignorePatterns(baseDir string, appDir string, ignoreDirs []string) ([]string, error) {
+	patterns := append([]string{}, defaultIgnorePatterns...)
+	if appDir != "" {
+		patterns = append(patterns, filepath.ToSlash(filepath.Join(appDir, "build")))
+	}
+	for _, dir := range ignoreDirs {
+		patterns = append(patterns, filepath.ToSlash(filepath.Clean(dir)))
+	}
+
+	rootPatterns, err := readIgnoreFile(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	patterns = append(patterns, rootPatterns...)
+
+	if appDir != "" && filepath.Clean(appDir) != "." {
+		appPatterns, err := readIgnoreFile(filepath.Join(baseDir, appDir))
+		if err != nil {
+			return nil, err
+		}
+		prefix := filepath.ToSlash(filepath.Clean(appDir)) + "/"
+		for _, pattern := range appPatterns {
+			if strings.HasPrefix(pattern, "!") {
+				patterns = append(patterns, "!"+prefix+pattern[1:])
+			} else {
+				patterns = append(patterns, prefix+pattern)
+			}
+		}
+	}
+	return patterns, nil
+}
+
+// readIgnoreFile reads the patterns of the first ignore file found in dir.
+func readIgnoreFile(dir string) ([]string, error) {
+	for _, name := range ignoreFileNames {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		patterns, err := ignorefile.ReadAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		return patterns, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,168 @@
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+)
+
+const testManifestJSON = `{
+    "schemaVersion": "1.7.0",
+    "acapPackageConf": {
+        "setup": {
+            "appName": "testapp",
+            "vendor": "Acme",
+            "runMode": "respawn",
+            "version": "1.2.3"
+        }
+    }
+}
+`
+
+// testManifest parses a manifest for the tests.
+func testManifest(t *testing.T, data string) *axmanifest.ApplicationManifestSchema {
+	t.Helper()
+	var m axmanifest.ApplicationManifestSchema
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		t.Fatal(err)
+	}
+	return &m
+}
+
+// writeFiles creates the files of a map from slash separated paths to content below dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// buildContextTar returns the tarball of the build context of the app in dir/app.
+func buildContextTar(t *testing.T, dir string) []byte {
+	t.Helper()
+	bc := &BuildConfiguration{
+		Manifest:     testManifest(t, testManifestJSON),
+		AppDirectory: "app",
+		ContextDir:   dir,
+		OutputDir:    "build",
+	}
+	info := BuildInfo{Version: "1.2.3", Commit: "abc", BuildTime: time.Unix(1700000000, 0)}
+	rc, err := createBuildContext(io.Discard, dir, bc, info, goToolchain{Version: "1.22.0"}, []byte("notices"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestBuildContext(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                  "module example.com/test\n",
+		".goxisignore":            "secrets\n",
+		"secrets/key.pem":         "key",
+		"app/main.go":             "package main\n",
+		"app/manifest.json":       testManifestJSON,
+		"app/LICENSE":             "MIT",
+		"app/.goxisignore":        "*.log\n!keep.log\ndocs\n",
+		"app/debug.log":           "ignored",
+		"app/keep.log":            "kept",
+		"app/docs/index.md":       "ignored",
+		"app/_draft.go":           "package main\n",
+		"app/_private/notes.txt":  "ignored",
+		"app/.netrc":              "machine cam password secret",
+		"app/sub/_netrc":          "machine cam password secret",
+		"app/build/old.eap":       "ignored",
+		"app/Makefile":            "replaced by the generated one",
+		"app/THIRD_PARTY_NOTICES": "replaced by the generated one",
+		".git/HEAD":               "ref: refs/heads/main",
+	})
+
+	first := buildContextTar(t, dir)
+	// Touching the sources must not change the tarball
+	later := time.Now().Add(time.Hour)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		return os.Chtimes(path, later, later)
+	})
+	second := buildContextTar(t, dir)
+	if !bytes.Equal(first, second) {
+		t.Error("two builds of the same sources give different build contexts")
+	}
+
+	var files, generated []string
+	contents := make(map[string]string)
+	tr := tar.NewReader(bytes.NewReader(first))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !header.ModTime.Equal(contextEpoch) || header.Uid != 0 || header.Gid != 0 || header.Uname != "" || header.Gname != "" {
+			t.Errorf("%s has the header %+v, want a normalized one", header.Name, header)
+		}
+		content, _ := io.ReadAll(tr)
+		contents[header.Name] = string(content)
+		// The files of the sources are 0644, the generated ones 0600
+		if header.Mode == 0600 {
+			generated = append(generated, header.Name)
+		} else {
+			files = append(files, header.Name)
+		}
+	}
+
+	wantFiles := []string{".goxisignore", "app", "app/.goxisignore", "app/LICENSE", "app/keep.log", "app/main.go", "app/manifest.json", "app/sub", "go.mod"}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("files = %q, want %q", files, wantFiles)
+	}
+	wantGenerated := []string{toolchainDir + "/version", "Dockerfile", "app/Makefile", "app/" + NoticesFile}
+	sort.Strings(wantGenerated)
+	if !reflect.DeepEqual(generated, wantGenerated) {
+		t.Errorf("generated files = %q, want %q", generated, wantGenerated)
+	}
+	if !strings.HasPrefix(contents["app/Makefile"], ".PHONY: build") || contents["app/"+NoticesFile] != "notices" {
+		t.Error("the generated files do not replace the files of the application")
+	}
+	if contents[toolchainDir+"/version"] != "1.22.0\n" {
+		t.Errorf("toolchain version = %q", contents[toolchainDir+"/version"])
+	}
+}
+
+func TestIgnorePatterns(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".dockerignore":     "node_modules\n",
+		"app/.goxisignore":  "*.tmp\n!important.tmp\n",
+		"app/.dockerignore": "not used, .goxisignore comes first\n",
+	})
+	got, err := ignorePatterns(dir, "app", []string{"app/web/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(append([]string{}, defaultIgnorePatterns...), "app/build", "app/web", "node_modules", "app/*.tmp", "!app/important.tmp")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ignorePatterns() = %q, want %q", got, want)
+	}
+}
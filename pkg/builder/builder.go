@@ -0,0 +1,376 @@
+// Package builder builds ACAP applications written with goxis in Docker and
+// installs them on Axis devices. It is the build pipeline of goxisbuilder.
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+)
+
+// Steps of a build, as named in events and StepError.
+const (
+	StepValidate  = "validate"
+	StepGenerate  = "generate"
+	StepNotices   = "notices"
+	StepVersion   = "version"
+	StepImage     = "image"
+	StepContainer = "container"
+	StepCopy      = "copy"
+	StepDeploy    = "deploy"
+	StepCleanup   = "cleanup"
+)
+
+// cleanupTimeout limits the removal of containers and images, which also runs
+// after the context of the build was canceled.
+const cleanupTimeout = 30 * time.Second
+
+// StepError is returned when a step of a build fails.
+type StepError struct {
+	Build string
+	Step  string
+	Err   error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("%s step failed: %v", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNoArtifacts is returned when the container did not produce an eap file.
+var ErrNoArtifacts = errors.New("there is no file in the docker context archive /opt/build, but at least .eap acap file should be there")
+
+// Result describes a finished build.
+type Result struct {
+	Name      string
+	ImageName string
+	// ImageID is the ID of the built image as reported by the daemon.
+	ImageID string
+	// Artifacts are the eap files copied to the output directory, empty with NotCopy.
+	Artifacts []Artifact
+	// Info is the version and git state injected into the binary.
+	Info     BuildInfo
+	Duration time.Duration
+}
+
+// Builder runs the build of one BuildConfiguration, create it with New.
+type Builder struct {
+	Config *BuildConfiguration
+	Docker *client.Client
+	// Events receives the progress of the build, it may be nil.
+	Events EventSink
+
+	log       io.Writer
+	logEvents *eventLogWriter
+	// notices is the THIRD_PARTY_NOTICES file of the eap, nil without one.
+	notices []byte
+	// params are the regenerated params files by their path in the build
+	// context, the ones that differ from the application directory.
+	params map[string][]byte
+}
+
+// New returns a Builder for bc. The output of the build is written to
+// bc.Log with the camera password redacted, and every line is also sent to
+// events as a log event.
+func New(cli *client.Client, bc *BuildConfiguration, events EventSink) *Builder {
+	b := &Builder{Config: bc, Docker: cli, Events: events}
+	out := bc.Log
+	if out == nil {
+		out = io.Discard
+	}
+	if events != nil {
+		b.logEvents = &eventLogWriter{b: b}
+		out = io.MultiWriter(out, b.logEvents)
+	}
+	// Never let the camera password reach the output
+	b.log = newRedactWriter(out, bc.Pwd)
+	return b
+}
+
+// Build builds the Docker image, runs a container from it, copies the eap
+// files to the output directory and installs or starts the application if
+// the configuration asks for it. The container is always removed, and so is
+// the image when its build did not finish, also when ctx is canceled.
+func (b *Builder) Build(ctx context.Context) (result *Result, err error) {
+	bc := b.Config
+	start := time.Now()
+	b.emit(Event{Type: EventBuildStarted, Message: bc.ImageName})
+	defer func() {
+		b.flushLog()
+		b.emit(Event{Type: EventBuildFinished, Error: ErrorString(err), DurationMs: time.Since(start).Milliseconds()})
+	}()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Catch mistakes in the manifest before minutes of Docker work
+	finish := b.startStep(StepValidate)
+	if err := finish(ValidateManifest(bc)); err != nil {
+		return nil, err
+	}
+
+	finish = b.startStep(StepGenerate)
+	if err := finish(b.regenerateParams()); err != nil {
+		return nil, err
+	}
+
+	finish = b.startStep(StepNotices)
+	if err := finish(b.collectNotices()); err != nil {
+		return nil, err
+	}
+
+	finish = b.startStep(StepVersion)
+	info, err := b.buildInfo()
+	if err := finish(err); err != nil {
+		return nil, err
+	}
+
+	containerID := ""
+	previousImage := b.imageID(ctx)
+	imageBuilt := false
+	defer func() {
+		// An image tagged by a build that did not finish is incomplete
+		removeImage := false
+		if !imageBuilt {
+			currentImage := b.imageID(context.WithoutCancel(ctx))
+			removeImage = currentImage != "" && currentImage != previousImage
+		}
+		finish := b.startStep(StepCleanup)
+		if cleanupErr := finish(b.cleanup(ctx, containerID, removeImage)); cleanupErr != nil {
+			result, err = nil, errors.Join(err, cleanupErr)
+		}
+	}()
+
+	result = &Result{Name: bc.Name(), ImageName: bc.ImageName, Info: info}
+	finish = b.startStep(StepImage)
+	buildCtx, cancel := withTimeout(ctx, bc.BuildTimeout)
+	imageID, err := b.dockerBuild(buildCtx, info)
+	err = timeoutError(buildCtx, bc.BuildTimeout, err)
+	cancel()
+	b.reportDiagnostics(err)
+	if err := finish(err); err != nil {
+		return nil, err
+	}
+	imageBuilt = true
+	result.ImageID = imageID
+
+	// Create and start container
+	finish = b.startStep(StepContainer)
+	containerID, err = createContainer(ctx, b.Docker, bc.ImageName)
+	if err := finish(err); err != nil {
+		return nil, err
+	}
+
+	// With -nocopy the eap only leaves the container when it has to be installed
+	eapPath := ""
+	if !bc.NotCopy || bc.DoInstall {
+		destDir := bc.OutputDir
+		if bc.NotCopy {
+			destDir, err = os.MkdirTemp("", "goxisbuilder")
+			if err != nil {
+				return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+			}
+			defer os.RemoveAll(destDir)
+		}
+		finish = b.startStep(StepCopy)
+		copyCtx, cancel := withTimeout(ctx, bc.CopyTimeout)
+		copied, err := copyFromContainer(copyCtx, b.Docker, containerID, destDir)
+		err = timeoutError(copyCtx, bc.CopyTimeout, err)
+		cancel()
+		if err := finish(err); err != nil {
+			return nil, err
+		}
+		eapPath = copied[0]
+		if !bc.NotCopy {
+			for _, eap := range copied {
+				artifact, err := describeArtifact(eap)
+				if err != nil {
+					return nil, err
+				}
+				artifact.Version, artifact.Commit, artifact.Dirty = info.Version, info.Commit, info.Dirty
+				b.emit(Event{Type: EventArtifact, Artifact: &artifact})
+				result.Artifacts = append(result.Artifacts, artifact)
+			}
+		}
+	} else {
+		fmt.Fprintln(b.log, "Copy eap file skipped")
+	}
+
+	if bc.DoInstall || bc.DoStart {
+		finish = b.startStep(StepDeploy)
+		if err := finish(b.Deploy(eapPath)); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// buildInfo collects the version and git state of the sources.
+func (b *Builder) buildInfo() (BuildInfo, error) {
+	contextDir, err := b.Config.contextDir()
+	if err != nil {
+		return BuildInfo{}, err
+	}
+	info, err := collectBuildInfo(b.Config, contextDir)
+	if err != nil {
+		return BuildInfo{}, err
+	}
+	fmt.Fprintf(b.log, "Building %s\n", info)
+	return info, nil
+}
+
+// Deploy installs eapPath and/or starts the application as requested by the configuration.
+func (b *Builder) Deploy(eapPath string) error {
+	bc := b.Config
+	camera := NewCamera(bc.Ip, bc.Pwd)
+	appName := bc.Manifest.ACAPPackageConf.Setup.AppName
+	if bc.DoInstall {
+		fmt.Fprintf(b.log, "Installing %s on %s...\n", filepath.Base(eapPath), bc.Ip)
+		err := camera.Install(eapPath)
+		b.emitInstall("install", filepath.Base(eapPath), err)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(b.log, "Application installed")
+	}
+	if bc.DoStart {
+		err := camera.Start(appName)
+		b.emitInstall("start", appName, err)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(b.log, "Application started")
+	}
+	return nil
+}
+
+// reportDiagnostics prints a summary of the toolchain errors of a failed
+// image build and emits them as events.
+func (b *Builder) reportDiagnostics(err error) {
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		return
+	}
+	printDiagnostics(b.log, buildErr.Diagnostics)
+	for _, d := range buildErr.Diagnostics {
+		b.emit(Event{Type: EventDiagnostic, Diagnostic: &d})
+	}
+}
+
+// cleanup removes the container, if any, and the image of the build when
+// removeImage is set. It runs with its own deadline, so it also works after
+// ctx was canceled, and reports what was removed.
+func (b *Builder) cleanup(ctx context.Context, containerID string, removeImage bool) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+	defer cancel()
+
+	var removed []string
+	var errs []error
+	if containerID != "" {
+		if err := b.Docker.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove container %s: %w", shortID(containerID), err))
+		} else {
+			removed = append(removed, "container "+shortID(containerID))
+		}
+	}
+	if removeImage {
+		if _, err := b.Docker.ImageRemove(ctx, b.Config.ImageName, image.RemoveOptions{PruneChildren: true}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove unfinished image %s: %w", b.Config.ImageName, err))
+		} else {
+			removed = append(removed, "unfinished image "+b.Config.ImageName)
+		}
+	}
+	if len(removed) > 0 {
+		msg := "Removed " + strings.Join(removed, " and ")
+		fmt.Fprintln(b.log, msg)
+		b.emit(Event{Type: EventCleanup, Message: msg})
+	}
+	return errors.Join(errs...)
+}
+
+// imageID returns the ID of the image the build is tagged with, "" if there is none.
+func (b *Builder) imageID(ctx context.Context) string {
+	inspect, _, err := b.Docker.ImageInspectWithRaw(ctx, b.Config.ImageName)
+	if err != nil {
+		return ""
+	}
+	return inspect.ID
+}
+
+// withTimeout is context.WithTimeout, without a deadline if d is zero.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// timeoutError names the timeout when err was caused by ctx running out of time.
+func timeoutError(ctx context.Context, d time.Duration, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", d, context.DeadlineExceeded)
+	}
+	return err
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// startStep emits the start of a build step. The returned function emits
+// its end and wraps the error of the step in a StepError.
+func (b *Builder) startStep(name string) func(err error) error {
+	start := time.Now()
+	b.emit(Event{Type: EventStepStarted, Step: name})
+	return func(err error) error {
+		b.emit(Event{Type: EventStepFinished, Step: name, Error: ErrorString(err), DurationMs: time.Since(start).Milliseconds()})
+		if err != nil {
+			return &StepError{Build: b.Config.Name(), Step: name, Err: err}
+		}
+		return nil
+	}
+}
+
+func (b *Builder) emitInstall(action, pkg string, err error) {
+	b.emit(Event{
+		Type:    EventInstall,
+		Error:   ErrorString(err),
+		Install: &InstallResult{Camera: b.Config.Ip, Action: action, Package: pkg, OK: err == nil},
+	})
+}
+
+// emit sends e to the event sink, if any, with the time and build name filled in.
+func (b *Builder) emit(e Event) {
+	if b.Events == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	e.Build = b.Config.Name()
+	b.Events.Emit(e)
+}
+
+func (b *Builder) flushLog() {
+	if w, ok := b.log.(*redactWriter); ok {
+		w.Flush()
+	}
+	if b.logEvents != nil {
+		b.logEvents.Flush()
+	}
+}
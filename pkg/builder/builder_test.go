@@ -0,0 +1,386 @@
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/client"
+)
+
+const (
+	testImage     = "testapp:aarch64-12.7.0"
+	testContainer = "c0ffee0123456789"
+)
+
+// fakeDocker is a Docker daemon answering the API calls of a build. Build and
+// archive answer the image build and the copy of the eap files.
+type fakeDocker struct {
+	mu      sync.Mutex
+	image   string // ID testImage resolves to, blank for none
+	calls   []string
+	build   http.HandlerFunc
+	archive http.HandlerFunc
+}
+
+var apiVersionRegex = regexp.MustCompile(`^/v[\d.]+`)
+
+func (d *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := apiVersionRegex.ReplaceAllString(r.URL.Path, "")
+	call := r.Method + " " + path
+	d.mu.Lock()
+	d.calls = append(d.calls, call)
+	image := d.image
+	d.mu.Unlock()
+
+	switch call {
+	case "GET /images/" + testImage + "/json":
+		if image == "" {
+			http.Error(w, `{"message":"No such image"}`, http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, `{"Id":"`+image+`"}`)
+	case "POST /build":
+		io.Copy(io.Discard, r.Body)
+		d.build(w, r)
+	case "POST /containers/create":
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"Id":"`+testContainer+`"}`)
+	case "POST /containers/" + testContainer + "/start", "DELETE /containers/" + testContainer:
+		w.WriteHeader(http.StatusNoContent)
+	case "GET /containers/" + testContainer + "/archive":
+		d.archive(w, r)
+	case "DELETE /images/" + testImage:
+		io.WriteString(w, `[{"Deleted":"`+image+`"}]`)
+	default:
+		http.Error(w, `{"message":"unexpected call"}`, http.StatusNotImplemented)
+	}
+}
+
+// tag makes testImage resolve to id, like a build that got to its tag.
+func (d *fakeDocker) tag(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.image = id
+}
+
+func (d *fakeDocker) called(call string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Contains(d.calls, call)
+}
+
+// newFakeBuilder returns a Builder of the app in a temporary directory whose
+// Docker client talks to d.
+func newFakeBuilder(t *testing.T, d *fakeDocker) *Builder {
+	t.Helper()
+	srv := httptest.NewServer(d)
+	t.Cleanup(srv.Close)
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+srv.Listener.Addr().String()), client.WithVersion("1.45"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":            "module example.com/app\n\ngo 1.22\n",
+		"app/main.go":       "package main\n",
+		"app/manifest.json": testManifestJSON,
+		"app/LICENSE":       "MIT",
+	})
+	bc := &BuildConfiguration{
+		Manifest:     testManifest(t, testManifestJSON),
+		ManifestPath: "manifest.json",
+		AppDirectory: "app",
+		ContextDir:   dir,
+		OutputDir:    filepath.Join(dir, "build"),
+		Sdk:          DefaultSdk,
+		Version:      DefaultSdkVersion,
+		ImageName:    testImage,
+	}
+	if err := ConfigureArchitecture("aarch64", bc); err != nil {
+		t.Fatal(err)
+	}
+	return New(cli, bc, nil)
+}
+
+// buildSucceeds answers an image build that tags the image.
+func (d *fakeDocker) buildSucceeds(w http.ResponseWriter, r *http.Request) {
+	d.tag("sha256:new")
+	io.WriteString(w, `{"aux":{"ID":"sha256:new"}}`+"\n"+`{"stream":"Successfully built new\n"}`+"\n")
+}
+
+// eapArchive answers the copy of /opt/build with one eap file.
+func eapArchive(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "build/testapp_1_2_3_aarch64.eap", Mode: 0644, Size: 3, Typeflag: tar.TypeReg})
+	tw.Write([]byte("eap"))
+	tw.Close()
+	w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(`{"name":"build","mode":2147484141}`)))
+	w.Write(buf.Bytes())
+}
+
+func TestBuildCleanup(t *testing.T) {
+	tests := []struct {
+		name            string
+		previous        string // image before the build
+		build           func(d *fakeDocker) http.HandlerFunc
+		archive         http.HandlerFunc
+		wantErr         string
+		wantContainer   bool // whether the container is removed
+		wantImageRemove bool
+	}{
+		{
+			name:          "success",
+			build:         func(d *fakeDocker) http.HandlerFunc { return d.buildSucceeds },
+			archive:       eapArchive,
+			wantContainer: true,
+		},
+		{
+			name: "image build fails after the tag",
+			build: func(d *fakeDocker) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					d.tag("sha256:partial")
+					io.WriteString(w, `{"stream":"Step 1/2 : RUN make\n"}`+"\n"+`{"error":"make failed"}`+"\n")
+				}
+			},
+			wantErr:         "make failed",
+			wantImageRemove: true,
+		},
+		{
+			name:     "image build fails and keeps the previous image",
+			previous: "sha256:old",
+			build: func(d *fakeDocker) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					io.WriteString(w, `{"error":"make failed"}`+"\n")
+				}
+			},
+			wantErr: "make failed",
+		},
+		{
+			name:  "copy fails",
+			build: func(d *fakeDocker) http.HandlerFunc { return d.buildSucceeds },
+			archive: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"message":"no such path"}`, http.StatusNotFound)
+			},
+			wantErr:       "no such path",
+			wantContainer: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDocker{image: tt.previous, archive: tt.archive}
+			d.build = tt.build(d)
+			b := newFakeBuilder(t, d)
+
+			result, err := b.Build(context.Background())
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Build() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Build() = %v, %v, want %q", result, err, tt.wantErr)
+			}
+			if got := d.called("DELETE /containers/" + testContainer); got != tt.wantContainer {
+				t.Errorf("container removed: %v, want %v", got, tt.wantContainer)
+			}
+			if got := d.called("DELETE /images/" + testImage); got != tt.wantImageRemove {
+				t.Errorf("image removed: %v, want %v", got, tt.wantImageRemove)
+			}
+			if tt.wantErr == "" && (len(result.Artifacts) != 1 || result.ImageID != "sha256:new") {
+				t.Errorf("result %+v", result)
+			}
+		})
+	}
+}
+
+func TestBuildCanceled(t *testing.T) {
+	tests := []struct {
+		name            string
+		inBuild         bool // canceled during the image build, else during the copy
+		wantContainer   bool
+		wantImageRemove bool
+	}{
+		{name: "during the image build", inBuild: true, wantImageRemove: true},
+		{name: "during the copy", wantContainer: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			// The request blocks until the client gives up on it
+			blocked := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				cancel()
+				<-r.Context().Done()
+			}
+			d := &fakeDocker{}
+			if tt.inBuild {
+				d.build = func(w http.ResponseWriter, r *http.Request) {
+					d.tag("sha256:partial")
+					blocked(w, r)
+				}
+			} else {
+				d.build, d.archive = d.buildSucceeds, blocked
+			}
+			b := newFakeBuilder(t, d)
+
+			if _, err := b.Build(ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Build() = %v, want context.Canceled", err)
+			}
+			if got := d.called("DELETE /containers/" + testContainer); got != tt.wantContainer {
+				t.Errorf("container removed: %v, want %v", got, tt.wantContainer)
+			}
+			if got := d.called("DELETE /images/" + testImage); got != tt.wantImageRemove {
+				t.Errorf("image removed: %v, want %v", got, tt.wantImageRemove)
+			}
+		})
+	}
+}
+
+func TestBuildCanceledBefore(t *testing.T) {
+	d := &fakeDocker{}
+	b := newFakeBuilder(t, d)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.Build(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Build() = %v, want context.Canceled", err)
+	}
+	if len(d.calls) > 0 {
+		t.Errorf("a canceled build called Docker: %v", d.calls)
+	}
+	if _, err := os.Stat(b.Config.OutputDir); err == nil {
+		t.Error("a canceled build created the output directory")
+	}
+}
+
+// recordEvents returns an EventSink that appends the events to a slice, and
+// a function returning a copy of that slice.
+func recordEvents() (EventSink, func() []Event) {
+	var mu sync.Mutex
+	var events []Event
+	sink := EventSinkFunc(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+	return sink, func() []Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(events)
+	}
+}
+
+func TestBuildEvents(t *testing.T) {
+	d := &fakeDocker{archive: eapArchive}
+	d.build = func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"stream":"curl -u root:hunter2 http://camera\n"}`+"\n")
+		d.buildSucceeds(w, r)
+	}
+	var log bytes.Buffer
+	sink, events := recordEvents()
+	b := newFakeBuilder(t, d)
+	b.Config.Pwd, b.Config.Log = "hunter2", &log
+	b = New(b.Docker, b.Config, sink)
+
+	result, err := b.Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build() = %v", err)
+	}
+
+	got := events()
+	if len(got) == 0 || got[0].Type != EventBuildStarted || got[0].Message != testImage {
+		t.Fatalf("events do not start with %s of %s: %+v", EventBuildStarted, testImage, got)
+	}
+	if last := got[len(got)-1]; last.Type != EventBuildFinished || last.Error != "" {
+		t.Errorf("last event %+v, want a successful %s", last, EventBuildFinished)
+	}
+	var steps, logLines []string
+	var artifacts []Artifact
+	for _, e := range got {
+		if e.Build != "aarch64" {
+			t.Errorf("event %+v is not of build aarch64", e)
+		}
+		switch e.Type {
+		case EventStepStarted:
+			steps = append(steps, e.Step)
+		case EventStepFinished:
+			if e.Error != "" {
+				t.Errorf("step %s failed: %s", e.Step, e.Error)
+			}
+		case EventLog:
+			logLines = append(logLines, e.Message)
+		case EventArtifact:
+			artifacts = append(artifacts, *e.Artifact)
+		}
+	}
+	wantSteps := []string{StepValidate, StepGenerate, StepNotices, StepVersion, StepImage, StepContainer, StepCopy, StepCleanup}
+	if !slices.Equal(steps, wantSteps) {
+		t.Errorf("steps %v, want %v", steps, wantSteps)
+	}
+
+	sum := sha256.Sum256([]byte("eap"))
+	if len(artifacts) != 1 || artifacts[0].Size != 3 || artifacts[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("artifact events %+v, want one of the eap file", artifacts)
+	}
+	if !reflect.DeepEqual(artifacts, result.Artifacts) {
+		t.Errorf("artifact events %+v, result artifacts %+v", artifacts, result.Artifacts)
+	}
+
+	// The password is redacted in the log and in the log events
+	allLines := strings.Join(logLines, "\n")
+	for name, out := range map[string]string{"log": log.String(), "log events": allLines} {
+		if strings.Contains(out, "hunter2") || !strings.Contains(out, "root:********") {
+			t.Errorf("%s do not redact the password:\n%s", name, out)
+		}
+	}
+}
+
+func TestBuildStepError(t *testing.T) {
+	d := &fakeDocker{}
+	d.build = d.buildSucceeds
+	d.archive = func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"no such path"}`, http.StatusNotFound)
+	}
+	sink, events := recordEvents()
+	b := newFakeBuilder(t, d)
+	b = New(b.Docker, b.Config, sink)
+
+	_, err := b.Build(context.Background())
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.Step != StepCopy || stepErr.Build != "aarch64" {
+		t.Fatalf("Build() = %v, want a StepError of step %s", err, StepCopy)
+	}
+	if !strings.Contains(stepErr.Err.Error(), "no such path") {
+		t.Errorf("StepError wraps %v, want the copy error", stepErr.Err)
+	}
+
+	got := events()
+	var failed []string
+	for _, e := range got {
+		if e.Type == EventStepFinished && e.Error != "" {
+			failed = append(failed, e.Step)
+		}
+	}
+	if !slices.Equal(failed, []string{StepCopy}) {
+		t.Errorf("failed steps %v, want only %s", failed, StepCopy)
+	}
+	if last := got[len(got)-1]; last.Type != EventBuildFinished || last.Error != err.Error() {
+		t.Errorf("last event %+v, want %s with %q", last, EventBuildFinished, err)
+	}
+}
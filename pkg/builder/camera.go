@@ -0,0 +1,183 @@
+package builder
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/icholy/digest"
+)
+
+// NewCameraClient returns an HTTP client that authenticates as root via digest auth.
+// Cameras usually run with self signed certificates, so verification is skipped.
+func NewCameraClient(pwd string) *http.Client {
+	return &http.Client{
+		Transport: &digest.Transport{
+			Username: "root",
+			Password: pwd,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+}
+
+// Camera talks to the application API (VAPIX) of an Axis device.
+type Camera struct {
+	// BaseURL is the scheme and host of the device, e.g. https://10.0.0.48.
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewCamera returns a Camera for ip that authenticates as root with pwd.
+func NewCamera(ip, pwd string) *Camera {
+	return &Camera{
+		BaseURL: "https://" + ip,
+		Client:  NewCameraClient(pwd),
+	}
+}
+
+// VapixError is an "Error: <code>" answer of the application API.
+type VapixError struct {
+	Action string
+	Code   string
+}
+
+// vapixErrorCodes describes the error codes of control.cgi and upload.cgi.
+var vapixErrorCodes = map[string]string{
+	"1":  "the package could not be uploaded or verified",
+	"4":  "the application was not found",
+	"5":  "the application is not compatible with the device",
+	"6":  "the application is already running",
+	"7":  "the application is not running",
+	"10": "the application could not be started",
+}
+
+func (e *VapixError) Error() string {
+	if desc, ok := vapixErrorCodes[e.Code]; ok {
+		return fmt.Sprintf("%s failed: %s (Error: %s)", e.Action, desc, e.Code)
+	}
+	return fmt.Sprintf("%s failed: Error: %s", e.Action, e.Code)
+}
+
+// ErrUnauthorized is returned when the device rejects the credentials.
+var ErrUnauthorized = errors.New("unauthorized, either the password is incorrect or the camera does not support digest auth -> check root.Network.HTTP.AuthenticationPolicy via https://<ip>/axis-cgi/param.cgi?action=list, should be set to digest")
+
+// Install uploads an eap file via upload.cgi, which installs it on the device.
+func (c *Camera) Install(eapPath string) error {
+	eap, err := os.Open(eapPath)
+	if err != nil {
+		return err
+	}
+	defer eap.Close()
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("packfil", filepath.Base(eapPath))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, eap); err != nil {
+		return fmt.Errorf("failed to read %s: %w", eapPath, err)
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	resp, err := c.Client.Post(c.BaseURL+"/axis-cgi/applications/upload.cgi", mw.FormDataContentType(), body)
+	if err != nil {
+		return fmt.Errorf("upload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkVapixResponse("install", resp)
+}
+
+// Start starts the application, an already running application is not an error.
+func (c *Camera) Start(appName string) error {
+	err := c.control("start", appName)
+	var vErr *VapixError
+	if errors.As(err, &vErr) && vErr.Code == "6" {
+		return nil
+	}
+	return err
+}
+
+// Stop stops the application, a stopped application is not an error.
+func (c *Camera) Stop(appName string) error {
+	err := c.control("stop", appName)
+	var vErr *VapixError
+	if errors.As(err, &vErr) && vErr.Code == "7" {
+		return nil
+	}
+	return err
+}
+
+// Restart restarts the application.
+func (c *Camera) Restart(appName string) error {
+	return c.control("restart", appName)
+}
+
+// Remove uninstalls the application.
+func (c *Camera) Remove(appName string) error {
+	return c.control("remove", appName)
+}
+
+// Ping checks that the device answers the application API with the configured password.
+func (c *Camera) Ping() error {
+	resp, err := c.Client.Get(c.BaseURL + "/axis-cgi/applications/list.cgi")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("camera responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// control runs a control.cgi action for appName.
+func (c *Camera) control(action, appName string) error {
+	query := url.Values{}
+	query.Set("action", action)
+	query.Set("package", appName)
+
+	resp, err := c.Client.Get(c.BaseURL + "/axis-cgi/applications/control.cgi?" + query.Encode())
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", action, err)
+	}
+	defer resp.Body.Close()
+	return checkVapixResponse(action, resp)
+}
+
+// checkVapixResponse turns a non OK application API response into an error.
+func checkVapixResponse(action string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s failed: camera responded with %s", action, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	msg := strings.TrimSpace(string(body))
+	if strings.HasPrefix(msg, "OK") {
+		return nil
+	}
+	if code, ok := strings.CutPrefix(msg, "Error:"); ok {
+		return &VapixError{Action: action, Code: strings.TrimSpace(code)}
+	}
+	return fmt.Errorf("%s failed: camera responded with %q", action, msg)
+}
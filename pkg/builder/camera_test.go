@@ -0,0 +1,162 @@
+package builder
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/icholy/digest"
+)
+
+const testPassword = "secret"
+
+// fakeCamera is a device answering the application API with digest auth as
+// root/testPassword. handle answers the authenticated requests.
+func fakeCamera(t *testing.T, handle http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	chal := &digest.Challenge{Realm: "AXIS_ACCC8E000000", Nonce: "0123456789abcdef", QOP: []string{"auth"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cred, err := digest.ParseCredentials(r.Header.Get("Authorization"))
+		if err == nil {
+			var want *digest.Credentials
+			want, err = digest.Digest(chal, digest.Options{
+				Method:   r.Method,
+				URI:      cred.URI,
+				Count:    cred.Nc,
+				Cnonce:   cred.Cnonce,
+				Username: "root",
+				Password: testPassword,
+			})
+			if err == nil && (cred.Username != "root" || cred.Response != want.Response) {
+				err = errors.New("wrong credentials")
+			}
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", chal.String())
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handle(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testCamera(srv *httptest.Server, pwd string) *Camera {
+	return &Camera{BaseURL: srv.URL, Client: NewCameraClient(pwd)}
+}
+
+func TestCameraInstall(t *testing.T) {
+	eap := filepath.Join(t.TempDir(), "app_1_0_0_aarch64.eap")
+	if err := os.WriteFile(eap, []byte("eap content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var gotPath, gotName, gotContent string
+	srv := fakeCamera(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		file, header, err := r.FormFile("packfil")
+		if err != nil {
+			t.Errorf("no packfil field: %v", err)
+			return
+		}
+		defer file.Close()
+		content, _ := io.ReadAll(file)
+		gotName, gotContent = header.Filename, string(content)
+		io.WriteString(w, "OK\n")
+	})
+
+	if err := testCamera(srv, testPassword).Install(eap); err != nil {
+		t.Fatalf("Install() = %v", err)
+	}
+	if gotPath != "/axis-cgi/applications/upload.cgi" {
+		t.Errorf("uploaded to %s", gotPath)
+	}
+	if gotName != "app_1_0_0_aarch64.eap" || gotContent != "eap content" {
+		t.Errorf("uploaded %q with %q", gotName, gotContent)
+	}
+}
+
+func TestCameraUnauthorized(t *testing.T) {
+	srv := fakeCamera(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "OK")
+	})
+	camera := testCamera(srv, "wrong")
+	if err := camera.Start("app"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Start() = %v, want ErrUnauthorized", err)
+	}
+	if err := camera.Ping(); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Ping() = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestCameraControl(t *testing.T) {
+	start := func(c *Camera) error { return c.Start("app") }
+	stop := func(c *Camera) error { return c.Stop("app") }
+	tests := []struct {
+		name    string
+		run     func(c *Camera) error
+		action  string
+		answer  string
+		errCode string // blank for success
+	}{
+		{"start", start, "start", "OK", ""},
+		{"start running", start, "start", "Error: 6", ""},
+		{"stop", stop, "stop", "OK\n", ""},
+		{"stop stopped", stop, "stop", "Error: 7", ""},
+		{"start fails", start, "start", "Error: 10", "10"},
+		{"stop not found", stop, "stop", "Error: 4", "4"},
+		{"restart", func(c *Camera) error { return c.Restart("app") }, "restart", "OK", ""},
+		{"restart stopped", func(c *Camera) error { return c.Restart("app") }, "restart", "Error: 7", "7"},
+		{"remove", func(c *Camera) error { return c.Remove("app") }, "remove", "OK", ""},
+		{"remove not found", func(c *Camera) error { return c.Remove("app") }, "remove", "Error: 4", "4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeCamera(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/axis-cgi/applications/control.cgi" {
+					t.Errorf("request to %s", r.URL.Path)
+				}
+				if got := r.URL.Query().Get("action"); got != tt.action {
+					t.Errorf("action = %s, want %s", got, tt.action)
+				}
+				if got := r.URL.Query().Get("package"); got != "app" {
+					t.Errorf("package = %s, want app", got)
+				}
+				io.WriteString(w, tt.answer)
+			})
+			err := tt.run(testCamera(srv, testPassword))
+			if tt.errCode == "" {
+				if err != nil {
+					t.Errorf("got %v, want success", err)
+				}
+				return
+			}
+			var vErr *VapixError
+			if !errors.As(err, &vErr) || vErr.Code != tt.errCode || vErr.Action != tt.action {
+				t.Errorf("got %v, want VapixError %s of %s", err, tt.errCode, tt.action)
+			}
+		})
+	}
+}
+
+func TestCameraUnexpectedAnswer(t *testing.T) {
+	srv := fakeCamera(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("action") == "remove" {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "<html>maintenance</html>")
+	})
+	camera := testCamera(srv, testPassword)
+	var vErr *VapixError
+	if err := camera.Start("app"); err == nil || errors.As(err, &vErr) {
+		t.Errorf("Start() = %v, want a plain error", err)
+	}
+	if err := camera.Remove("app"); err == nil || errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Remove() = %v, want a status error", err)
+	}
+}
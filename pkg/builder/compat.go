@@ -0,0 +1,347 @@
+package builder
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSdk is the SDK goxisbuilder builds with.
+const DefaultSdk = "acap-native-sdk"
+
+// DefaultSchemaVersion is the manifest schema of new projects for an SDK
+// release whose schema is unknown.
+const DefaultSchemaVersion = "1.7.0"
+
+//go:embed compat.yaml
+var embeddedCompat []byte
+
+// CompatDB is the compatibility of the ACAP SDKs, manifest schemas and
+// architectures with AXIS OS, see compat.yaml.
+type CompatDB struct {
+	// LTS lists the long term support tracks of AXIS OS.
+	LTS           []string            `yaml:"lts"`
+	SDKs          []SdkRelease        `yaml:"sdks"`
+	Schemas       []SchemaRelease     `yaml:"schemas"`
+	Architectures map[string][]string `yaml:"architectures"`
+}
+
+// SdkRelease is a release of an SDK. Firmware and Until are the first and
+// last AXIS OS versions it supports, Until is blank when unknown. Schema is
+// the newest manifest schema its acap-build knows, blank when unknown. Goxis
+// is the goxis version new projects for it require, blank for the one
+// goxisbuilder was built with.
+type SdkRelease struct {
+	Sdk      string `yaml:"sdk" json:"sdk"`
+	Version  string `yaml:"version" json:"version"`
+	Firmware string `yaml:"firmware" json:"firmware"`
+	Until    string `yaml:"until,omitempty" json:"until,omitempty"`
+	Ubuntu   string `yaml:"ubuntu" json:"ubuntu"`
+	Schema   string `yaml:"schema,omitempty" json:"schema,omitempty"`
+	Goxis    string `yaml:"goxis,omitempty" json:"goxis,omitempty"`
+}
+
+// SchemaRelease is a manifest schema version and the first AXIS OS version
+// supporting it. Fields are the dotted paths of the manifest fields it introduced.
+type SchemaRelease struct {
+	Version  string   `yaml:"version" json:"version"`
+	Firmware string   `yaml:"firmware" json:"firmware"`
+	Fields   []string `yaml:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// DefaultCompatDB returns the compatibility data built into goxisbuilder.
+func DefaultCompatDB() *CompatDB {
+	db, err := parseCompatDB(embeddedCompat)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded compat.yaml: %v", err))
+	}
+	return db
+}
+
+// UserCompatFile returns the path of the compatibility file in the user config directory.
+func UserCompatFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "goxisbuilder", "compat.yaml")
+}
+
+// LoadCompatDB returns the built-in compatibility data merged with the user
+// compatibility file, if it exists, and then with path, unless it is blank.
+func LoadCompatDB(path string) (*CompatDB, error) {
+	db := DefaultCompatDB()
+	files := []string{}
+	if user := UserCompatFile(); user != "" {
+		if _, err := os.Stat(user); err == nil {
+			files = append(files, user)
+		}
+	}
+	if path != "" {
+		files = append(files, path)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compatibility file: %w", err)
+		}
+		override, err := parseCompatDB(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		db.merge(override)
+	}
+	return db, nil
+}
+
+func parseCompatDB(data []byte) (*CompatDB, error) {
+	var db CompatDB
+	if err := yaml.Unmarshal(data, &db); err != nil {
+		return nil, err
+	}
+	for _, sdk := range db.SDKs {
+		if sdk.Sdk == "" || sdk.Version == "" || !isFirmwareVersion(sdk.Firmware) || (sdk.Until != "" && !isFirmwareVersion(sdk.Until)) {
+			return nil, fmt.Errorf("sdk %q version %q needs a sdk, version and firmware like 11.11", sdk.Sdk, sdk.Version)
+		}
+	}
+	for _, schema := range db.Schemas {
+		if schema.Version == "" || !isFirmwareVersion(schema.Firmware) {
+			return nil, fmt.Errorf("schema %q needs a version and firmware like 11.11", schema.Version)
+		}
+	}
+	return &db, nil
+}
+
+// merge adds the entries of o to db, replacing the entries they share.
+func (db *CompatDB) merge(o *CompatDB) {
+	for _, lts := range o.LTS {
+		if !slices.Contains(db.LTS, lts) {
+			db.LTS = append(db.LTS, lts)
+		}
+	}
+	for _, sdk := range o.SDKs {
+		i := slices.IndexFunc(db.SDKs, func(s SdkRelease) bool { return s.Sdk == sdk.Sdk && s.Version == sdk.Version })
+		if i < 0 {
+			db.SDKs = append(db.SDKs, sdk)
+		} else {
+			db.SDKs[i] = sdk
+		}
+	}
+	for _, schema := range o.Schemas {
+		i := slices.IndexFunc(db.Schemas, func(s SchemaRelease) bool { return s.Version == schema.Version })
+		if i < 0 {
+			db.Schemas = append(db.Schemas, schema)
+		} else {
+			db.Schemas[i] = schema
+		}
+	}
+	if db.Architectures == nil {
+		db.Architectures = make(map[string][]string)
+	}
+	for arch, chips := range o.Architectures {
+		db.Architectures[arch] = chips
+	}
+}
+
+// Sdk returns the release of an SDK.
+func (db *CompatDB) Sdk(sdk, version string) (SdkRelease, bool) {
+	i := slices.IndexFunc(db.SDKs, func(s SdkRelease) bool { return s.Sdk == sdk && s.Version == version })
+	if i < 0 {
+		return SdkRelease{}, false
+	}
+	return db.SDKs[i], true
+}
+
+// Schema returns the release of a manifest schema.
+func (db *CompatDB) Schema(version string) (SchemaRelease, bool) {
+	i := slices.IndexFunc(db.Schemas, func(s SchemaRelease) bool { return s.Version == version })
+	if i < 0 {
+		return SchemaRelease{}, false
+	}
+	return db.Schemas[i], true
+}
+
+// Firmware describes the AXIS OS versions an SDK supports, e.g. "11.10 and
+// later until 11.11 (LTS)".
+func (db *CompatDB) Firmware(sdk SdkRelease) string {
+	switch {
+	case sdk.Until == sdk.Firmware:
+		return db.withLTS(sdk.Firmware)
+	case sdk.Until != "":
+		return sdk.Firmware + " and later until " + db.withLTS(sdk.Until)
+	default:
+		return db.withLTS(sdk.Firmware) + " and later"
+	}
+}
+
+func (db *CompatDB) withLTS(firmware string) string {
+	if slices.Contains(db.LTS, firmware) {
+		return firmware + " (LTS)"
+	}
+	return firmware
+}
+
+// FindChip returns the name and architecture of a chip, e.g. ARTPEC-8 and
+// aarch64 for "artpec8". The name is matched ignoring case, spaces and dashes.
+func (db *CompatDB) FindChip(chip string) (name, arch string, ok bool) {
+	normalize := strings.NewReplacer(" ", "", "-", "")
+	want := strings.ToLower(normalize.Replace(chip))
+	for arch, chips := range db.Architectures {
+		for _, c := range chips {
+			if strings.ToLower(normalize.Replace(c)) == want {
+				return c, arch, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Recommendation is the SDK, Ubuntu image and manifest schema to build for
+// an AXIS OS version with.
+type Recommendation struct {
+	Firmware string        `json:"firmware"`
+	Sdk      SdkRelease    `json:"sdk"`
+	Schema   SchemaRelease `json:"schema"`
+}
+
+// ForFirmware recommends the newest Native SDK release supporting an AXIS OS
+// version, and the newest manifest schema both support.
+func (db *CompatDB) ForFirmware(firmware string) (Recommendation, error) {
+	rec := Recommendation{Firmware: firmware}
+	if !isFirmwareVersion(firmware) {
+		return rec, fmt.Errorf("invalid AXIS OS version %q, expected e.g. 11.11", firmware)
+	}
+	found := false
+	for _, sdk := range db.SDKs {
+		if sdk.Sdk != DefaultSdk || !db.supports(sdk, firmware) {
+			continue
+		}
+		if !found || CompareVersions(sdk.Version, rec.Sdk.Version) > 0 {
+			rec.Sdk, found = sdk, true
+		}
+	}
+	if !found {
+		return rec, fmt.Errorf("no %s release supports AXIS OS %s", DefaultSdk, firmware)
+	}
+	found = false
+	for _, schema := range db.Schemas {
+		if CompareVersions(schema.Firmware, firmware) > 0 || (rec.Sdk.Schema != "" && CompareVersions(schema.Version, rec.Sdk.Schema) > 0) {
+			continue
+		}
+		if !found || CompareVersions(schema.Version, rec.Schema.Version) > 0 {
+			rec.Schema, found = schema, true
+		}
+	}
+	if !found {
+		return rec, fmt.Errorf("no manifest schema supports AXIS OS %s", firmware)
+	}
+	return rec, nil
+}
+
+// supports reports whether an SDK release builds for an AXIS OS version.
+func (db *CompatDB) supports(sdk SdkRelease, firmware string) bool {
+	return CompareVersions(sdk.Firmware, firmware) <= 0 && (sdk.Until == "" || CompareVersions(firmware, sdk.Until) <= 0)
+}
+
+// ConfigureFirmware picks the SDK and Ubuntu version of bc for its
+// TargetFirmware, unless they are set already, and lowers the manifest schema to
+// the newest one the firmware supports. An SDK that does not support the
+// firmware is an error.
+func (db *CompatDB) ConfigureFirmware(bc *BuildConfiguration) (Recommendation, error) {
+	firmware := bc.TargetFirmware
+	rec, err := db.ForFirmware(firmware)
+	if err != nil {
+		return rec, err
+	}
+	if bc.SdkVersion == "" {
+		bc.SdkVersion = rec.Sdk.Version
+		if bc.UbunutVersion == "" {
+			bc.UbunutVersion = rec.Sdk.Ubuntu
+		}
+	} else if sdk, ok := db.Sdk(DefaultSdk, bc.SdkVersion); ok {
+		if !db.supports(sdk, firmware) {
+			return rec, fmt.Errorf("SDK %s supports AXIS OS %s, not %s, use SDK %s", sdk.Version, db.Firmware(sdk), firmware, rec.Sdk.Version)
+		}
+		if bc.UbunutVersion == "" {
+			bc.UbunutVersion = sdk.Ubuntu
+		}
+	}
+	if CompareVersions(bc.Manifest.SchemaVersion, rec.Schema.Version) > 0 {
+		bc.SchemaVersion = rec.Schema.Version
+	}
+	return rec, nil
+}
+
+// Compatibility describes the firmware and chips a build runs on, unknown
+// values are left empty.
+type Compatibility struct {
+	Sdk            string   `json:"sdk"`
+	SdkVersion     string   `json:"sdkVersion"`
+	Firmware       string   `json:"firmware,omitempty"`
+	SchemaVersion  string   `json:"schemaVersion"`
+	SchemaFirmware string   `json:"schemaFirmware,omitempty"`
+	Arch           string   `json:"arch"`
+	Chips          []string `json:"chips,omitempty"`
+}
+
+// Compatibility looks up the compatibility of a configured build.
+func (db *CompatDB) Compatibility(buildConfig *BuildConfiguration) Compatibility {
+	compat := Compatibility{
+		Sdk:           buildConfig.Sdk,
+		SdkVersion:    buildConfig.Version,
+		SchemaVersion: buildConfig.Manifest.SchemaVersion,
+		Arch:          buildConfig.Arch,
+		Chips:         db.Architectures[buildConfig.Arch],
+	}
+	if buildConfig.SchemaVersion != "" {
+		compat.SchemaVersion = buildConfig.SchemaVersion
+	}
+	if schema, ok := db.Schema(compat.SchemaVersion); ok {
+		compat.SchemaFirmware = schema.Firmware
+	}
+	if sdk, ok := db.Sdk(buildConfig.Sdk, buildConfig.Version); ok {
+		compat.Firmware = db.Firmware(sdk)
+	}
+	return compat
+}
+
+// isFirmwareVersion reports whether v is a dotted version like 11.11.
+func isFirmwareVersion(v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, part := range strings.Split(v, ".") {
+		if _, err := strconv.Atoi(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareVersions compares dotted numeric versions like 1.7.0 or 11.11,
+// missing parts count as 0.
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,150 @@
+package builder
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestForFirmware(t *testing.T) {
+	tests := []struct {
+		firmware   string
+		wantSdk    string
+		wantSchema string
+		wantErr    string
+	}{
+		{firmware: "11.11", wantSdk: "1.15", wantSchema: "1.7.0"},
+		{firmware: "10.12", wantSdk: "1.3", wantSchema: "1.3"},
+		{firmware: "11.7", wantSdk: "1.11", wantSchema: "1.4.0"},
+		// 12.6.0 knows schema 1.8.0, but AXIS OS 12.2 only 1.7.3
+		{firmware: "12.2", wantSdk: "12.6.0", wantSchema: "1.7.3"},
+		{firmware: "12.8", wantSdk: "12.7.0", wantSchema: "1.8.0"},
+		{firmware: "9.80", wantErr: "no acap-native-sdk release supports AXIS OS 9.80"},
+		{firmware: "11.x", wantErr: `invalid AXIS OS version "11.x"`},
+		{firmware: "", wantErr: "invalid AXIS OS version"},
+	}
+	db := DefaultCompatDB()
+	for _, tt := range tests {
+		rec, err := db.ForFirmware(tt.firmware)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ForFirmware(%q) = %+v, %v, want error %q", tt.firmware, rec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ForFirmware(%q): %v", tt.firmware, err)
+			continue
+		}
+		if rec.Sdk.Sdk != DefaultSdk || rec.Sdk.Version != tt.wantSdk || rec.Schema.Version != tt.wantSchema {
+			t.Errorf("ForFirmware(%q) = SDK %s %s, schema %s, want SDK %s, schema %s", tt.firmware, rec.Sdk.Sdk, rec.Sdk.Version, rec.Schema.Version, tt.wantSdk, tt.wantSchema)
+		}
+	}
+}
+
+func TestConfigureFirmware(t *testing.T) {
+	tests := []struct {
+		name                string
+		sdk, ubuntu, schema string
+		firmware            string
+		wantSdk, wantUbuntu string
+		wantSchema, wantErr string
+	}{
+		{name: "picks the SDK", firmware: "11.11", schema: "1.7.0", wantSdk: "1.15", wantUbuntu: "22.04"},
+		{name: "keeps the Ubuntu version", firmware: "11.11", ubuntu: "20.04", schema: "1.7.0", wantSdk: "1.15", wantUbuntu: "20.04"},
+		{name: "supported SDK", firmware: "11.11", sdk: "1.8", schema: "1.3.1", wantSdk: "1.8", wantUbuntu: "22.04"},
+		{name: "unknown SDK", firmware: "11.11", sdk: "1.99", schema: "1.7.0", wantSdk: "1.99"},
+		{name: "unsupported SDK", firmware: "11.11", sdk: "12.7.0", wantErr: "SDK 12.7.0 supports AXIS OS 12.7 and later, not 11.11, use SDK 1.15"},
+		{name: "lowers the schema", firmware: "11.8", schema: "1.8.0", wantSdk: "1.12", wantUbuntu: "22.04", wantSchema: "1.5.0"},
+		{name: "keeps an older schema", firmware: "12.7", schema: "1.3", wantSdk: "12.7.0", wantUbuntu: "24.04"},
+		{name: "firmware with no SDK", firmware: "9.80", wantErr: "no acap-native-sdk release supports AXIS OS 9.80"},
+	}
+	db := DefaultCompatDB()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := &BuildConfiguration{Manifest: testManifest(t, testManifestJSON), TargetFirmware: tt.firmware, SdkVersion: tt.sdk, UbunutVersion: tt.ubuntu}
+			bc.Manifest.SchemaVersion = tt.schema
+			_, err := db.ConfigureFirmware(bc)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bc.SdkVersion != tt.wantSdk || bc.UbunutVersion != tt.wantUbuntu || bc.SchemaVersion != tt.wantSchema {
+				t.Errorf("got SDK %q, Ubuntu %q, schema %q, want %q, %q, %q", bc.SdkVersion, bc.UbunutVersion, bc.SchemaVersion, tt.wantSdk, tt.wantUbuntu, tt.wantSchema)
+			}
+		})
+	}
+}
+
+func TestLoadCompatDBMerge(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	writeFiles(t, config, map[string]string{"goxisbuilder/compat.yaml": `
+lts: ["12.11", "11.11"]
+sdks:
+  - {sdk: acap-native-sdk, version: "12.7.0", firmware: "12.7", ubuntu: "24.10", schema: "1.8.0"}
+  - {sdk: acap-native-sdk, version: "12.8.0", firmware: "12.8", ubuntu: "24.04", schema: "1.8.1"}
+schemas:
+  - {version: "1.8.1", firmware: "12.8"}
+architectures:
+  aarch64: ["ARTPEC-8", "ARTPEC-9"]
+`})
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"compat.yaml": `
+sdks:
+  - {sdk: acap-native-sdk, version: "12.8.0", firmware: "12.8", ubuntu: "24.04", schema: "1.8.0"}
+`})
+
+	db, err := LoadCompatDB(filepath.Join(dir, "compat.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded := DefaultCompatDB()
+	if want := append(embedded.LTS, "12.11"); !reflect.DeepEqual(db.LTS, want) {
+		t.Errorf("LTS = %v, want %v", db.LTS, want)
+	}
+	if len(db.SDKs) != len(embedded.SDKs)+1 {
+		t.Errorf("%d SDKs, want %d", len(db.SDKs), len(embedded.SDKs)+1)
+	}
+	if sdk, _ := db.Sdk(DefaultSdk, "12.7.0"); sdk.Ubuntu != "24.10" {
+		t.Errorf("SDK 12.7.0 was not replaced by the user file: %+v", sdk)
+	}
+	// -compat is merged after the user file
+	if sdk, _ := db.Sdk(DefaultSdk, "12.8.0"); sdk.Schema != "1.8.0" {
+		t.Errorf("SDK 12.8.0 was not replaced by -compat: %+v", sdk)
+	}
+	if _, ok := db.Schema("1.8.1"); !ok {
+		t.Error("schema 1.8.1 was not added")
+	}
+	if chips := db.Architectures["aarch64"]; !reflect.DeepEqual(chips, []string{"ARTPEC-8", "ARTPEC-9"}) {
+		t.Errorf("aarch64 chips = %v", chips)
+	}
+	if chips := db.Architectures["armv7hf"]; !reflect.DeepEqual(chips, embedded.Architectures["armv7hf"]) {
+		t.Errorf("armv7hf chips = %v", chips)
+	}
+	if rec, err := db.ForFirmware("12.11"); err != nil || rec.Sdk.Version != "12.8.0" {
+		t.Errorf("ForFirmware(12.11) = %+v, %v", rec, err)
+	}
+	// The embedded data is not changed by merging
+	if sdk, _ := DefaultCompatDB().Sdk(DefaultSdk, "12.7.0"); sdk.Ubuntu != "24.04" {
+		t.Errorf("embedded SDK 12.7.0 changed: %+v", sdk)
+	}
+}
+
+func TestLoadCompatDBInvalid(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"compat.yaml": `sdks: [{sdk: acap-native-sdk, version: "13.0.0", firmware: "thirteen"}]`})
+	if _, err := LoadCompatDB(filepath.Join(dir, "compat.yaml")); err == nil {
+		t.Error("no error for an invalid firmware")
+	}
+	if _, err := LoadCompatDB(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("no error for a missing file")
+	}
+}
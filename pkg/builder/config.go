@@ -0,0 +1,216 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+)
+
+// BuildConfiguration defines the configuration parameters for building
+// the EAP application, including details such as architecture, manifest details,
+// and flags indicating whether to install the application, start it,
+// build examples, or watch logs.
+type BuildConfiguration struct {
+	Manifest      *axmanifest.ApplicationManifestSchema
+	ManifestPath  string
+	ImageName     string
+	Ip            string
+	Pwd           string
+	Arch          string
+	DoStart       bool
+	DoInstall     bool
+	NotCopy       bool
+	Prune         bool
+	AppDirectory  string
+	Sdk           string
+	UbunutVersion string
+	Version       string
+	GoArch        string
+	GoArm         string
+	CrossPrefix   string
+
+	Watch      bool
+	Dockerfile string
+	FilesToAdd string
+	SdkVersion string
+	IgnoreDirs []string
+	BuildTags  string
+	EnableUpx  bool
+	// Notices adds the licenses of the Go modules as THIRD_PARTY_NOTICES to the eap.
+	Notices bool
+	// LdFlags and GcFlags are added to the flags of 'go build', LdVars are
+	// "importpath.name=value" pairs set with -X and GoEnv are NAME=value
+	// environment variables of 'go build'.
+	LdFlags  string
+	GcFlags  string
+	LdVars   []string
+	GoEnv    []string
+	TrimPath bool
+	// Release refuses to build from a dirty git working tree. BuildNumber,
+	// if set, stamps a dev build number into the manifest version.
+	Release     bool
+	BuildNumber string
+	// GoVersion overrides the Go version of the go.mod of the application.
+	// GoToolchain is a local linux-amd64 archive of Go, or a directory of
+	// them, installed instead of downloading Go.
+	GoVersion   string
+	GoToolchain string
+	// TargetFirmware is the AXIS OS version the build is for, if known.
+	TargetFirmware string
+	// SchemaVersion overrides the schemaVersion of the manifest, e.g. to
+	// build for an older AXIS OS, see CompatDB.ConfigureFirmware.
+	SchemaVersion string
+	// Compat is the compatibility data the manifest is validated against,
+	// nil for the built-in data.
+	Compat *CompatDB
+	// Target is the name of the goxis.yaml target this build belongs to, if any.
+	Target string
+	// WorkspaceApp is the path of the app below the workspace root, if any.
+	WorkspaceApp string
+	// OutputDir receives the eap files and logs, "build" unless building a workspace.
+	OutputDir string
+	// ContextDir is the root of the Docker build context, blank for the
+	// current directory. AppDirectory is relative to it.
+	ContextDir string
+	// BuildTimeout and CopyTimeout limit the image build and the copy of the
+	// eap files out of the container, zero means no limit.
+	BuildTimeout time.Duration
+	CopyTimeout  time.Duration
+
+	// Log receives the build output, nothing is written when it is nil.
+	Log io.Writer
+}
+
+// Name identifies the build in logs, events and summaries.
+func (bc *BuildConfiguration) Name() string {
+	name := bc.Arch
+	if bc.Target != "" {
+		name = bc.Target + "-" + name
+	}
+	if bc.WorkspaceApp != "" {
+		name = strings.ReplaceAll(bc.WorkspaceApp, "/", "-") + "-" + name
+	}
+	return name
+}
+
+// contextDir returns the root of the build context on the host.
+func (bc *BuildConfiguration) contextDir() (string, error) {
+	if bc.ContextDir != "" {
+		return bc.ContextDir, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current dir: %w", err)
+	}
+	return dir, nil
+}
+
+// compat returns the compatibility data of the build.
+func (bc *BuildConfiguration) compat() *CompatDB {
+	if bc.Compat == nil {
+		return DefaultCompatDB()
+	}
+	return bc.Compat
+}
+
+// SupportedArchitectures lists the architectures in the order 'all' builds them.
+var SupportedArchitectures = []string{"aarch64", "armv7hf"}
+
+// ParseArchitectures splits a space- or comma-separated list of architectures,
+// where 'all' stands for every supported architecture.
+func ParseArchitectures(s string) ([]string, error) {
+	var archs []string
+	for _, arch := range strings.Fields(strings.ReplaceAll(s, ",", " ")) {
+		if arch == "all" {
+			return SupportedArchitectures, nil
+		}
+		if !slices.Contains(SupportedArchitectures, arch) {
+			return nil, fmt.Errorf("should be either aarch64, armv7hf or all, got %s", arch)
+		}
+		if !slices.Contains(archs, arch) {
+			archs = append(archs, arch)
+		}
+	}
+	if len(archs) == 0 {
+		return nil, errors.New("no architecture given")
+	}
+	return archs, nil
+}
+
+// ConfigureArchitecture sets up the build configuration based on the architecture.
+// The image name is unique per app, architecture and SDK so builds can run side by side.
+func ConfigureArchitecture(arch string, buildConfig *BuildConfiguration) error {
+	buildConfig.Arch = arch
+	switch arch {
+	case "aarch64":
+		buildConfig.GoArch = "arm64"
+		buildConfig.CrossPrefix = "aarch64-linux-gnu-"
+	case "armv7hf":
+		buildConfig.GoArch = "arm"
+		buildConfig.GoArm = "7"
+		buildConfig.CrossPrefix = "arm-linux-gnueabihf-"
+	default:
+		return fmt.Errorf("should be either aarch64 or armv7hf, got %s", arch)
+	}
+	appName := strings.ToLower(buildConfig.Manifest.ACAPPackageConf.Setup.AppName)
+	buildConfig.ImageName = fmt.Sprintf("%s:%s-%s", appName, arch, buildConfig.Version)
+	if buildConfig.Target != "" {
+		buildConfig.ImageName += "-" + buildConfig.Target
+	}
+	return nil
+}
+
+// The SDK and Ubuntu version used unless the configuration sets one.
+const (
+	DefaultSdkVersion    = "12.7.0"
+	DefaultUbuntuVersion = "24.04"
+)
+
+// ConfigureSdk sets up the build configuration based on the Sdk flags.
+func ConfigureSdk(buildConfig *BuildConfiguration) {
+	buildConfig.Sdk = DefaultSdk
+	if buildConfig.UbunutVersion == "" {
+		buildConfig.UbunutVersion = DefaultUbuntuVersion
+	}
+	if buildConfig.SdkVersion != "" {
+		buildConfig.Version = buildConfig.SdkVersion
+	} else {
+		buildConfig.Version = DefaultSdkVersion
+	}
+}
+
+// NormalizeGoBuildTags converts a user-provided tags string into a
+// comma-separated list per modern Go expectations. It accepts input in
+// either space- or comma-separated form, trims whitespace, removes empty
+// entries, and de-duplicates while preserving order.
+func NormalizeGoBuildTags(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	// Treat commas as separators, then split on any whitespace
+	s = strings.ReplaceAll(s, ",", " ")
+	parts := strings.Fields(s)
+	if len(parts) == 0 {
+		return ""
+	}
+	seen := make(map[string]struct{}, len(parts))
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+	}
+	return strings.Join(out, ",")
+}
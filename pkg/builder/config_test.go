@@ -0,0 +1,49 @@
+package builder
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseArchitectures(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr string
+	}{
+		{"aarch64", []string{"aarch64"}, ""},
+		{"armv7hf aarch64", []string{"armv7hf", "aarch64"}, ""},
+		{"aarch64, armv7hf,aarch64", []string{"aarch64", "armv7hf"}, ""},
+		{"all", SupportedArchitectures, ""},
+		{"aarch64 all", SupportedArchitectures, ""},
+		{"x86_64", nil, "should be either aarch64, armv7hf or all, got x86_64"},
+		{"aarch64,arm64", nil, "got arm64"},
+		{" , ", nil, "no architecture given"},
+	}
+	for _, tt := range tests {
+		got, err := ParseArchitectures(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseArchitectures(%q) = %v, %v, want %q", tt.in, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("ParseArchitectures(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestConfigureArchitecture(t *testing.T) {
+	bc := &BuildConfiguration{Manifest: testManifest(t, testManifestJSON), Version: "12.7.0", Target: "axis12"}
+	if err := ConfigureArchitecture("armv7hf", bc); err != nil {
+		t.Fatal(err)
+	}
+	if bc.GoArch != "arm" || bc.GoArm != "7" || bc.CrossPrefix != "arm-linux-gnueabihf-" || bc.ImageName != "testapp:armv7hf-12.7.0-axis12" {
+		t.Errorf("armv7hf: %s %s %s %s", bc.GoArch, bc.GoArm, bc.CrossPrefix, bc.ImageName)
+	}
+	if err := ConfigureArchitecture("mips", bc); err == nil {
+		t.Error("mips: no error")
+	}
+}
@@ -0,0 +1,122 @@
+package builder
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// containerAppDir is where the build context is copied to inside the image.
+const containerAppDir = "/opt/goaxis"
+
+// Kinds of diagnostics.
+const (
+	DiagnosticCompile = "compile"
+	DiagnosticVet     = "vet"
+	DiagnosticLink    = "link"
+)
+
+// Diagnostic is an error of the Go compiler, vet or the linker found in the
+// build output. File is a path on the host when the file belongs to the
+// build context, otherwise the path inside the container.
+type Diagnostic struct {
+	Kind    string `json:"kind"`
+	Package string `json:"package,omitempty"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	prefix := ""
+	if d.Kind == DiagnosticVet {
+		prefix = "vet: "
+	}
+	switch {
+	case d.File == "":
+		return fmt.Sprintf("%s: %s", d.Kind, d.Message)
+	case d.Column > 0:
+		return fmt.Sprintf("%s%s:%d:%d: %s", prefix, d.File, d.Line, d.Column, d.Message)
+	default:
+		return fmt.Sprintf("%s%s:%d: %s", prefix, d.File, d.Line, d.Message)
+	}
+}
+
+var (
+	// sourceDiagnosticRegex matches "file.go:12:5: message", optionally prefixed by "vet: "
+	sourceDiagnosticRegex = regexp.MustCompile(`^(vet: )?([\w./@+~-]+\.(?:go|c|h|s|cc|cpp)):(\d+)(?::(\d+))?: (.+)$`)
+	// linkDiagnosticRegex matches the errors of the Go linker and the C linker it runs
+	linkDiagnosticRegex = regexp.MustCompile(`(?:^|/)(?:link|(?:[\w-]+-)?ld(?:\.\w+)?): |undefined reference to`)
+)
+
+// diagnosticParser finds diagnostics in the lines of the build output and
+// maps container paths to host paths.
+type diagnosticParser struct {
+	// contextDir and appDir locate the application on the host, see BuildConfiguration.
+	contextDir string
+	appDir     string
+	pkg        string
+	found      []Diagnostic
+}
+
+// parse returns the diagnostic in line, if any.
+func (p *diagnosticParser) parse(line string) (Diagnostic, bool) {
+	line = strings.TrimSpace(line)
+	if pkg, ok := strings.CutPrefix(line, "# "); ok {
+		p.pkg = pkg
+		return Diagnostic{}, false
+	}
+
+	var d Diagnostic
+	if m := sourceDiagnosticRegex.FindStringSubmatch(line); m != nil {
+		d = Diagnostic{Kind: DiagnosticCompile, Package: p.pkg, File: p.hostPath(m[2]), Message: m[5]}
+		if m[1] != "" {
+			d.Kind = DiagnosticVet
+		}
+		d.Line, _ = strconv.Atoi(m[3])
+		d.Column, _ = strconv.Atoi(m[4])
+	} else if linkDiagnosticRegex.MatchString(line) {
+		d = Diagnostic{Kind: DiagnosticLink, Package: p.pkg, Message: line}
+	} else {
+		return Diagnostic{}, false
+	}
+
+	for _, f := range p.found {
+		if f == d {
+			return d, true
+		}
+	}
+	p.found = append(p.found, d)
+	return d, true
+}
+
+// hostPath maps a path printed inside the container to the host. Relative
+// paths are relative to the application directory, the working directory of
+// the build. Paths outside the build context, e.g. the module cache, are kept.
+func (p *diagnosticParser) hostPath(file string) string {
+	var rel string
+	if strings.HasPrefix(file, containerAppDir+"/") {
+		rel = strings.TrimPrefix(file, containerAppDir+"/")
+	} else if !path.IsAbs(file) {
+		rel = path.Join(p.appDir, file)
+	} else {
+		return file
+	}
+	return filepath.Join(p.contextDir, filepath.FromSlash(path.Clean(rel)))
+}
+
+// printDiagnostics writes a summary of the diagnostics to out.
+func printDiagnostics(out io.Writer, diagnostics []Diagnostic) {
+	if len(diagnostics) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\nBuild errors (%d):\n", len(diagnostics))
+	for _, d := range diagnostics {
+		fmt.Fprintf(out, "  %s\n", d)
+	}
+}
@@ -0,0 +1,92 @@
+package builder
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagnosticParser(t *testing.T) {
+	app := func(elem ...string) string { return filepath.Join(append([]string{"/ctx", "app"}, elem...)...) }
+	tests := []struct {
+		name   string
+		line   string
+		want   Diagnostic
+		wantOk bool
+	}{
+		{"compile", "./main.go:7:2: undefined: foo\n", Diagnostic{Kind: DiagnosticCompile, Package: "example.com/app", File: app("main.go"), Line: 7, Column: 2, Message: "undefined: foo"}, true},
+		{"compile subdirectory", "internal/cam/cam.go:12:5: syntax error: unexpected }", Diagnostic{Kind: DiagnosticCompile, Package: "example.com/app", File: app("internal", "cam", "cam.go"), Line: 12, Column: 5, Message: "syntax error: unexpected }"}, true},
+		{"container path", "/opt/goaxis/app/main.go:3: imported and not used", Diagnostic{Kind: DiagnosticCompile, Package: "example.com/app", File: app("main.go"), Line: 3, Message: "imported and not used"}, true},
+		{"module cache", "/root/go/pkg/mod/github.com/x/y@v1.0.0/y.go:9:1: missing return", Diagnostic{Kind: DiagnosticCompile, Package: "example.com/app", File: "/root/go/pkg/mod/github.com/x/y@v1.0.0/y.go", Line: 9, Column: 1, Message: "missing return"}, true},
+		{"cgo", "./axevent.c:20:10: fatal error: axsdk/axevent.h: No such file or directory", Diagnostic{Kind: DiagnosticCompile, Package: "example.com/app", File: app("axevent.c"), Line: 20, Column: 10, Message: "fatal error: axsdk/axevent.h: No such file or directory"}, true},
+		{"vet", "vet: ./main.go:10:2: fmt.Printf format %d has arg s of wrong type string", Diagnostic{Kind: DiagnosticVet, Package: "example.com/app", File: app("main.go"), Line: 10, Column: 2, Message: "fmt.Printf format %d has arg s of wrong type string"}, true},
+		{"go linker", "/usr/local/go/pkg/tool/linux_amd64/link: running aarch64-linux-gnu-gcc failed: exit status 1", Diagnostic{Kind: DiagnosticLink, Package: "example.com/app", Message: "/usr/local/go/pkg/tool/linux_amd64/link: running aarch64-linux-gnu-gcc failed: exit status 1"}, true},
+		{"c linker", "/usr/bin/aarch64-linux-gnu-ld: cannot find -laxevent", Diagnostic{Kind: DiagnosticLink, Package: "example.com/app", Message: "/usr/bin/aarch64-linux-gnu-ld: cannot find -laxevent"}, true},
+		{"undefined reference", "main.o: in function `run': undefined reference to `ax_event_new'", Diagnostic{Kind: DiagnosticLink, Package: "example.com/app", Message: "main.o: in function `run': undefined reference to `ax_event_new'"}, true},
+		{"acap-build error", "acap-build error: manifest.json: 'appName' is required", Diagnostic{}, false},
+		{"step", "Step 7/12 : RUN make", Diagnostic{}, false},
+		{"plain output", "go: downloading github.com/Cacsjep/goxis v1.2.0", Diagnostic{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &diagnosticParser{contextDir: "/ctx", appDir: "app"}
+			if _, ok := p.parse("# example.com/app\n"); ok {
+				t.Fatal("package line parsed as a diagnostic")
+			}
+			got, ok := p.parse(tt.line)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("parse(%q) = %#v, %v, want %#v, %v", tt.line, got, ok, tt.want, tt.wantOk)
+			}
+			if tt.wantOk != (len(p.found) == 1) {
+				t.Errorf("found %v", p.found)
+			}
+		})
+	}
+}
+
+func TestDiagnosticParserDuplicates(t *testing.T) {
+	p := &diagnosticParser{contextDir: "/ctx", appDir: "app"}
+	// go build and vet both report the same error
+	for _, line := range []string{"./main.go:7:2: undefined: foo", "./main.go:7:2: undefined: foo", "vet: ./main.go:7:2: undefined: foo"} {
+		p.parse(line)
+	}
+	if len(p.found) != 2 {
+		t.Errorf("found %v, want one compile and one vet diagnostic", p.found)
+	}
+}
+
+func TestHostPath(t *testing.T) {
+	tests := []struct {
+		appDir, file, want string
+	}{
+		{"app", "main.go", filepath.Join("/ctx", "app", "main.go")},
+		{"app", "./cmd/../main.go", filepath.Join("/ctx", "app", "main.go")},
+		{"", "main.go", filepath.Join("/ctx", "main.go")},
+		{"apps/cam", "/opt/goaxis/apps/cam/main.go", filepath.Join("/ctx", "apps", "cam", "main.go")},
+		{"app", "/opt/goaxis/shared/util.go", filepath.Join("/ctx", "shared", "util.go")},
+		{"app", "/usr/local/go/src/fmt/print.go", "/usr/local/go/src/fmt/print.go"},
+		{"app", "/opt/goaxisother/x.go", "/opt/goaxisother/x.go"},
+	}
+	for _, tt := range tests {
+		p := &diagnosticParser{contextDir: "/ctx", appDir: tt.appDir}
+		if got := p.hostPath(tt.file); got != tt.want {
+			t.Errorf("hostPath(%q) with appDir %q = %q, want %q", tt.file, tt.appDir, got, tt.want)
+		}
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	tests := []struct {
+		d    Diagnostic
+		want string
+	}{
+		{Diagnostic{Kind: DiagnosticCompile, File: "main.go", Line: 7, Column: 2, Message: "undefined: foo"}, "main.go:7:2: undefined: foo"},
+		{Diagnostic{Kind: DiagnosticCompile, File: "main.go", Line: 3, Message: "imported and not used"}, "main.go:3: imported and not used"},
+		{Diagnostic{Kind: DiagnosticVet, File: "main.go", Line: 10, Column: 2, Message: "unreachable code"}, "vet: main.go:10:2: unreachable code"},
+		{Diagnostic{Kind: DiagnosticLink, Message: "cannot find -laxevent"}, "link: cannot find -laxevent"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package builder
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// NewDockerClient connects to the Docker daemon configured in the environment.
+func NewDockerClient() (*client.Client, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	return cli, nil
+}
+
+// dockerBuild performs the Docker image build operation, processes the output
+// and returns the ID of the built image.
+func (b *Builder) dockerBuild(ctx context.Context, info BuildInfo) (string, error) {
+	bc := b.Config
+	contextDir, err := bc.contextDir()
+	if err != nil {
+		return "", err
+	}
+
+	tc, err := resolveToolchain(bc, contextDir)
+	if err != nil {
+		return "", err
+	}
+	if tc.Tarball != "" {
+		fmt.Fprintf(b.log, "Using Go %s (%s) from %s\n", tc.Version, tc.Source, tc.Tarball)
+	} else {
+		fmt.Fprintf(b.log, "Using Go %s (%s)\n", tc.Version, tc.Source)
+	}
+
+	fmt.Fprintln(b.log, "Building Docker image...")
+	buildContext, err := createBuildContext(b.log, contextDir, bc, info, tc, b.notices, b.params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create build context: %w", err)
+	}
+	defer buildContext.Close()
+
+	fmt.Fprintln(b.log, "Adding files to build context...")
+
+	files_to_add := ""
+	if bc.FilesToAdd != "" {
+		files := strings.Split(bc.FilesToAdd, " ")
+		for _, file := range files {
+			files_to_add += fmt.Sprintf("-a %s ", file)
+		}
+	}
+	if b.notices != nil {
+		files_to_add += fmt.Sprintf("-a %s ", NoticesFile)
+	}
+
+	options := types.ImageBuildOptions{
+		Dockerfile: "Dockerfile",
+		Tags:       []string{bc.ImageName},
+		BuildArgs: map[string]*string{
+			"ARCH":                 ptr(bc.Arch),
+			"GOLANG_VERSION":       ptr(tc.Version),
+			"SDK":                  ptr(bc.Sdk),
+			"UBUNTU_VERSION":       ptr(bc.UbunutVersion),
+			"VERSION":              ptr(bc.Version),
+			"GO_ARCH":              ptr(bc.GoArch),
+			"GO_ARM":               ptr(bc.GoArm),
+			"APP_NAME":             ptr(bc.Manifest.ACAPPackageConf.Setup.AppName),
+			"APP_MANIFEST":         ptr(bc.ManifestPath),
+			"GO_APP":               ptr(bc.AppDirectory),
+			"FILES_TO_ADD_TO_ACAP": ptr(files_to_add),
+			"GO_BUILD_TAGS":        ptr(bc.BuildTags),
+			"ENABLE_UPX":           ptr(boolToStr(bc.EnableUpx)),
+			"EAP_SUFFIX":           ptr(eapSuffix(bc)),
+		},
+		Remove:      true,
+		ForceRemove: true,
+		NoCache:     false,
+	}
+
+	fmt.Fprintln(b.log, "Starting Docker image build...")
+
+	buildResponse, err := b.Docker.ImageBuild(ctx, buildContext, options)
+	if err != nil {
+		return "", fmt.Errorf("unable to build image: %w", err)
+	}
+	defer buildResponse.Body.Close()
+	return readBuildStream(buildResponse.Body, b.log, diagnosticParser{contextDir: bc.ContextDir, appDir: bc.AppDirectory})
+}
+
+// eapSuffix is appended to the eap file name, so builds of different SDKs
+// and targets never produce the same file.
+func eapSuffix(bc *BuildConfiguration) string {
+	suffix := "_sdk_" + bc.Version
+	if bc.Target != "" {
+		suffix += "_" + bc.Target
+	}
+	return suffix
+}
+
+// createContainer creates and starts a Docker container from an image
+func createContainer(ctx context.Context, cli *client.Client, imageName string) (string, error) {
+	resp, err := cli.ContainerCreate(ctx, &container.Config{
+		Image: imageName,
+	}, nil, nil, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("container creation failed: %w", err)
+	}
+
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return "", fmt.Errorf("container start failed: %w", err)
+	}
+
+	return resp.ID, nil
+}
+
+// copyFromContainer copies the eap files of our build result into destDir and returns their paths.
+func copyFromContainer(ctx context.Context, cli *client.Client, id string, destDir string) ([]string, error) {
+	copyFromContainer, _, err := cli.CopyFromContainer(ctx, id, "/opt/build")
+	if err != nil {
+		return nil, err
+	}
+	defer copyFromContainer.Close()
+
+	if err := os.MkdirAll(destDir, os.FileMode(0755)); err != nil {
+		return nil, fmt.Errorf("failed to create build directory (local): %w", err)
+	}
+
+	tr := tar.NewReader(copyFromContainer)
+	var eaps []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break // End of archive
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			outputPath := filepath.Join(destDir, filepath.Base(header.Name))
+			outputFile, err := os.Create(outputPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create file that is extracted from docker context archiv, File:%s from docker folder /opt/build, Error: %w", header.Name, err)
+			}
+			defer outputFile.Close()
+
+			if _, err := io.Copy(outputFile, tr); err != nil {
+				return nil, fmt.Errorf("failed to copy file that is extracted from docker context archiv, File:%s from docker folder /opt/build, Error: %w", header.Name, err)
+			}
+			if strings.HasSuffix(outputPath, ".eap") {
+				eaps = append(eaps, outputPath)
+			}
+		}
+	}
+
+	if len(eaps) == 0 {
+		return nil, ErrNoArtifacts
+	}
+
+	return eaps, nil
+}
+
+func boolToStr(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,141 @@
+package builder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event types.
+const (
+	EventBuildStarted  = "build_started"
+	EventBuildFinished = "build_finished"
+	EventStepStarted   = "step_started"
+	EventStepFinished  = "step_finished"
+	EventLog           = "log"
+	EventArtifact      = "artifact"
+	EventCompatibility = "compatibility"
+	EventInstall       = "install"
+	EventDiagnostic    = "diagnostic"
+	EventCleanup       = "cleanup"
+	EventSummary       = "summary"
+	EventError         = "error"
+)
+
+// Event reports the progress of a build. Only the fields that belong to its
+// type are set.
+type Event struct {
+	Time          time.Time      `json:"time"`
+	Type          string         `json:"type"`
+	Build         string         `json:"build,omitempty"`
+	Step          string         `json:"step,omitempty"`
+	Message       string         `json:"message,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	DurationMs    int64          `json:"durationMs,omitempty"`
+	Artifact      *Artifact      `json:"artifact,omitempty"`
+	Compatibility *Compatibility `json:"compatibility,omitempty"`
+	Install       *InstallResult `json:"install,omitempty"`
+	Diagnostic    *Diagnostic    `json:"diagnostic,omitempty"`
+	Summary       *BuildSummary  `json:"summary,omitempty"`
+}
+
+// EventSink receives the events of builds. Builds running concurrently call
+// Emit from several goroutines.
+type EventSink interface {
+	Emit(e Event)
+}
+
+// EventSinkFunc adapts a function to an EventSink.
+type EventSinkFunc func(e Event)
+
+func (f EventSinkFunc) Emit(e Event) {
+	f(e)
+}
+
+// Artifact describes a file produced by a build.
+type Artifact struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// Version, Commit and Dirty trace the artifact back to its sources.
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+	Dirty   bool   `json:"dirty,omitempty"`
+}
+
+// describeArtifact reads the size and SHA-256 of the file at path.
+func describeArtifact(path string) (Artifact, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return Artifact{Path: path, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// InstallResult is the outcome of an install or start on the camera.
+type InstallResult struct {
+	Camera  string `json:"camera"`
+	Action  string `json:"action"`
+	Package string `json:"package"`
+	OK      bool   `json:"ok"`
+}
+
+// BuildSummary counts the builds of a run.
+type BuildSummary struct {
+	Passed int `json:"passed"`
+	Total  int `json:"total"`
+}
+
+// ErrorString returns the message of err, or "" for nil.
+func ErrorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// eventLogWriter emits complete lines as log events.
+type eventLogWriter struct {
+	b   *Builder
+	buf []byte
+}
+
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emits a trailing line that has no newline yet.
+func (w *eventLogWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *eventLogWriter) writeLine(line []byte) {
+	msg := strings.TrimRight(string(line), "\r")
+	if strings.TrimSpace(msg) == "" {
+		return
+	}
+	w.b.emit(Event{Type: EventLog, Message: msg})
+}
@@ -0,0 +1,310 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+)
+
+// JSONObject is a JSON object that keeps the order of its keys, so edited
+// manifests stay diffable.
+type JSONObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func NewJSONObject() *JSONObject {
+	return &JSONObject{values: make(map[string]interface{})}
+}
+
+func (o *JSONObject) Get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// Set sets the value of key, new keys are appended.
+func (o *JSONObject) Set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// Keys returns the keys of o in order.
+func (o *JSONObject) Keys() []string {
+	return o.keys
+}
+
+func (o *JSONObject) Remove(key string) bool {
+	if _, ok := o.values[key]; !ok {
+		return false
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Object returns the object at key, creating it if create is set. It
+// returns nil if the value is missing or not an object.
+func (o *JSONObject) Object(key string, create bool) *JSONObject {
+	if v, ok := o.values[key].(*JSONObject); ok {
+		return v
+	}
+	if _, ok := o.values[key]; ok || !create {
+		return nil
+	}
+	v := NewJSONObject()
+	o.Set(key, v)
+	return v
+}
+
+// Path returns the object at the dotted path below o, see object.
+func (o *JSONObject) Path(path string, create bool) *JSONObject {
+	for _, key := range strings.Split(path, ".") {
+		if o = o.Object(key, create); o == nil {
+			return nil
+		}
+	}
+	return o
+}
+
+// SetPath sets the value at a dotted path below o, creating missing
+// objects. "[]" after the last key appends v to its array.
+func (o *JSONObject) SetPath(path string, v interface{}) error {
+	parent := o
+	if dir, key, nested := cutLast(path, "."); nested {
+		if parent = o.Path(dir, true); parent == nil {
+			return fmt.Errorf("%s is not an object", dir)
+		}
+		path = key
+	}
+	name, appending := strings.CutSuffix(path, "[]")
+	if name == "" {
+		return fmt.Errorf("empty key in %q", path)
+	}
+	if !appending {
+		parent.Set(name, v)
+		return nil
+	}
+	current, ok := parent.Get(name)
+	items, isArray := current.([]interface{})
+	if ok && !isArray {
+		return fmt.Errorf("%s is not an array", name)
+	}
+	parent.Set(name, append(items, v))
+	return nil
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// RemovePath removes a dotted path below o, "[]" after a key applies the
+// rest of the path to every element of its array. It reports whether
+// anything was removed.
+func (o *JSONObject) RemovePath(path string) bool {
+	key, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		return o.Remove(key)
+	}
+	if name, ok := strings.CutSuffix(key, "[]"); ok {
+		items, _ := o.values[name].([]interface{})
+		removed := false
+		for _, item := range items {
+			if obj, ok := item.(*JSONObject); ok && obj.RemovePath(rest) {
+				removed = true
+			}
+		}
+		return removed
+	}
+	child := o.Object(key, false)
+	return child != nil && child.RemovePath(rest)
+}
+
+// ParseJSON parses a single JSON value, objects become *JSONObject and
+// numbers json.Number.
+func ParseJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := parseJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// parseJSONValue reads the next value of dec, objects become *JSONObject and
+// numbers json.Number.
+func parseJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := NewJSONObject()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			value, err := parseJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(key, value)
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		items := []interface{}{}
+		for dec.More() {
+			value, err := parseJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		_, err := dec.Token()
+		return items, err
+	default:
+		return tok, nil
+	}
+}
+
+// writeJSONValue writes v indented by indent per level, like json.MarshalIndent.
+func writeJSONValue(buf *bytes.Buffer, v interface{}, indent string, depth int) error {
+	newline := "\n" + strings.Repeat(indent, depth+1)
+	switch val := v.(type) {
+	case *JSONObject:
+		if len(val.keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteByte('{')
+		for i, key := range val.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(newline)
+			writeJSONScalar(buf, key)
+			buf.WriteString(": ")
+			if err := writeJSONValue(buf, val.values[key], indent, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("\n" + strings.Repeat(indent, depth) + "}")
+	case []interface{}:
+		if len(val) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i, item := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(newline)
+			if err := writeJSONValue(buf, item, indent, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("\n" + strings.Repeat(indent, depth) + "]")
+	default:
+		return writeJSONScalar(buf, val)
+	}
+	return nil
+}
+
+// writeJSONScalar writes a string, number, bool or null without escaping HTML characters.
+func writeJSONScalar(buf *bytes.Buffer, v interface{}) error {
+	var scalar bytes.Buffer
+	enc := json.NewEncoder(&scalar)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimSuffix(scalar.Bytes(), []byte("\n")))
+	return nil
+}
+
+// ManifestDoc is a manifest.json loaded for editing. It keeps the key order,
+// the indentation and the trailing newline of the file.
+type ManifestDoc struct {
+	Root     *JSONObject
+	indent   string
+	trailing string
+}
+
+// ParseManifestDoc parses the content of a manifest.json.
+func ParseManifestDoc(data []byte) (*ManifestDoc, error) {
+	value, err := ParseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := value.(*JSONObject)
+	if !ok {
+		return nil, errors.New("not a JSON object")
+	}
+
+	doc := &ManifestDoc{Root: root, indent: "    "}
+	// The first indented line tells the indentation of the file
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != "" && trimmed != line {
+			doc.indent = line[:len(line)-len(trimmed)]
+			break
+		}
+	}
+	doc.trailing = string(data[len(bytes.TrimRight(data, " \t\r\n")):])
+	return doc, nil
+}
+
+// SchemaVersion returns the schemaVersion of the manifest.
+func (d *ManifestDoc) SchemaVersion() string {
+	v, _ := d.Root.Get("schemaVersion")
+	s, _ := v.(string)
+	return s
+}
+
+// Setup returns acapPackageConf.setup.
+func (d *ManifestDoc) Setup() *JSONObject {
+	return d.Root.Path("acapPackageConf.setup", true)
+}
+
+// Configuration returns acapPackageConf.configuration.
+func (d *ManifestDoc) Configuration() *JSONObject {
+	return d.Root.Path("acapPackageConf.configuration", true)
+}
+
+// Bytes returns the content of the manifest, it fails if the result does
+// not load as a manifest.
+func (d *ManifestDoc) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSONValue(&buf, d.Root, d.indent, 0); err != nil {
+		return nil, err
+	}
+	buf.WriteString(d.trailing)
+
+	var check axmanifest.ApplicationManifestSchema
+	if err := json.Unmarshal(buf.Bytes(), &check); err != nil {
+		return nil, fmt.Errorf("the edited manifest is invalid: %w", err)
+	}
+	return buf.Bytes(), nil
+}
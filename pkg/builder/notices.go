@@ -0,0 +1,179 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NoticesFile is the file of the eap that holds the licenses of the Go
+// modules the application is built from, next to its LICENSE.
+const NoticesFile = "THIRD_PARTY_NOTICES"
+
+// licenseFileRegex matches the license and notice files of a module.
+var licenseFileRegex = regexp.MustCompile(`(?i)^(licen[cs]e|copying|notice|patents)([.-].*)?$`)
+
+// listedPackage is the part of 'go list -json' the notices are collected from.
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	Root       string
+	Standard   bool
+	Module     *struct {
+		Path    string
+		Version string
+		Dir     string
+		Main    bool
+		Replace *struct {
+			Path    string
+			Version string
+			Dir     string
+		}
+	}
+}
+
+// moduleNotice is a module linked into the application with its license files.
+type moduleNotice struct {
+	Path    string
+	Version string
+	Dir     string
+}
+
+// CollectNotices returns the licenses of the Go modules and the standard
+// library the application in dir is built from, for the architecture and
+// build tags of bc. It runs 'go list -deps' on the host, so the module
+// graph is the one the build in Docker uses. The license of the standard
+// library comes from the host Go, its heading names both versions when it
+// is not goVersion, the Go version of the build.
+func CollectNotices(bc *BuildConfiguration, dir, goVersion string) ([]byte, error) {
+	goCmd := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH="+bc.GoArch, "CGO_ENABLED=1")
+		if bc.GoArm != "" {
+			cmd.Env = append(cmd.Env, "GOARM="+bc.GoArm)
+		}
+		cmd.Env = append(cmd.Env, bc.GoEnv...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("go %s: %w\n%s", strings.Join(args, " "), err, stderr.Bytes())
+		}
+		return out, nil
+	}
+	args := []string{"list", "-deps", "-json"}
+	if bc.BuildTags != "" {
+		args = append(args, "-tags", bc.BuildTags)
+	}
+	out, err := goCmd(append(args, ".")...)
+	if err != nil {
+		return nil, err
+	}
+	// The version of the toolchain go list ran with, after a switch by the go.mod
+	hostVersion, err := goCmd("env", "GOVERSION")
+	if err != nil {
+		return nil, err
+	}
+	stdVersion := strings.TrimSpace(string(hostVersion))
+	if build := "go" + goVersion; goVersion != "" && build != stdVersion {
+		stdVersion = fmt.Sprintf("(from the host %s, the build uses %s)", stdVersion, build)
+	}
+
+	modules := make(map[string]*moduleNotice)
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg listedPackage
+		if err := dec.Decode(&pkg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read the output of go list: %w", err)
+		}
+		switch {
+		case pkg.Standard:
+			if _, ok := modules["std"]; !ok {
+				modules["std"] = &moduleNotice{Path: "Go standard library", Version: stdVersion, Dir: pkg.Root}
+			}
+		case pkg.Module != nil && !pkg.Module.Main:
+			m := pkg.Module
+			if _, ok := modules[m.Path]; ok {
+				continue
+			}
+			notice := &moduleNotice{Path: m.Path, Version: m.Version, Dir: m.Dir}
+			if m.Replace != nil {
+				notice.Version = strings.TrimSpace(m.Replace.Path + " " + m.Replace.Version)
+				notice.Dir = m.Replace.Dir
+			}
+			if notice.Dir == "" {
+				// Vendored modules have no directory of their own
+				notice.Dir = strings.TrimSuffix(pkg.Dir, filepath.FromSlash(strings.TrimPrefix(pkg.ImportPath, m.Path)))
+			}
+			modules[m.Path] = notice
+		}
+	}
+
+	paths := make([]string, 0, len(modules))
+	for path := range modules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s includes the following Go modules, their licenses are reproduced below.\n", bc.Manifest.ACAPPackageConf.Setup.AppName)
+	for _, path := range paths {
+		m := modules[path]
+		fmt.Fprintf(&buf, "\n%s\n%s\n%s\n", strings.Repeat("=", 80), strings.TrimSpace(m.Path+" "+m.Version), strings.Repeat("=", 80))
+		entries, _ := os.ReadDir(m.Dir)
+		found := false
+		for _, entry := range entries {
+			if entry.IsDir() || !licenseFileRegex.MatchString(entry.Name()) {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(m.Dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "\n%s:\n\n%s\n", entry.Name(), bytes.TrimRight(content, "\n"))
+			found = true
+		}
+		if !found {
+			fmt.Fprintln(&buf, "\nNo license file found.")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// collectNotices collects the notices of the application of b, unless
+// disabled. They need Go on the host.
+func (b *Builder) collectNotices() error {
+	bc := b.Config
+	b.notices = nil
+	if !bc.Notices {
+		return nil
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		return fmt.Errorf("Go is not installed, it is needed to collect the licenses of the Go modules into %s, disable them with -notices=false: %w", NoticesFile, err)
+	}
+	contextDir, err := bc.contextDir()
+	if err != nil {
+		return err
+	}
+	tc, err := resolveToolchain(bc, contextDir)
+	if err != nil {
+		return err
+	}
+	notices, err := CollectNotices(bc, filepath.Join(contextDir, bc.AppDirectory), tc.Version)
+	if err != nil {
+		return fmt.Errorf("failed to collect the licenses of the Go modules: %w", err)
+	}
+	fmt.Fprintf(b.log, "Collected the licenses of the Go modules into %s\n", NoticesFile)
+	b.notices = notices
+	return nil
+}
@@ -0,0 +1,62 @@
+package builder
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCollectNotices(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.21\n",
+		"main.go": "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println() }\n",
+	})
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := strings.TrimSpace(string(out))
+
+	bc := &BuildConfiguration{Manifest: testManifest(t, testManifestJSON), GoArch: "arm64"}
+	tests := []struct {
+		goVersion, want string
+	}{
+		{strings.TrimPrefix(host, "go"), "\nGo standard library " + host + "\n"},
+		{"1.0.0", "\nGo standard library (from the host " + host + ", the build uses go1.0.0)\n"},
+	}
+	for _, tt := range tests {
+		notices, err := CollectNotices(bc, dir, tt.goVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := string(notices)
+		if !strings.HasPrefix(s, "testapp includes the following Go modules") {
+			t.Errorf("unexpected heading:\n%s", s)
+		}
+		if !strings.Contains(s, tt.want) || !strings.Contains(s, "\nLICENSE:\n") {
+			t.Errorf("notices for Go %s do not contain %q and the LICENSE of the standard library", tt.goVersion, tt.want)
+		}
+		if strings.Contains(s, "example.com/app") {
+			t.Error("the main module is listed")
+		}
+	}
+}
+
+func TestCollectNoticesWithoutGo(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	b := New(nil, &BuildConfiguration{Manifest: testManifest(t, testManifestJSON), ContextDir: t.TempDir(), Notices: true}, nil)
+	if err := b.collectNotices(); err == nil || !strings.Contains(err.Error(), "-notices=false") {
+		t.Errorf("got %v, want an error suggesting -notices=false", err)
+	}
+
+	b.Config.Notices = false
+	if err := b.collectNotices(); err != nil || b.notices != nil {
+		t.Errorf("disabled notices: %v, %q", err, b.notices)
+	}
+}
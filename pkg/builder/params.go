@@ -0,0 +1,302 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+)
+
+// ParamsFile is the file name 'generate params' writes by default.
+const ParamsFile = "params_gen.go"
+
+// paramsHeader starts every generated params file, builds regenerate the
+// files of the application directory that start with it.
+const paramsHeader = "// Code generated by goxisbuilder generate params. DO NOT EDIT."
+
+// param is a paramConfig entry of the manifest with its Go representation.
+type param struct {
+	Name    string
+	Type    string
+	Ident   string
+	GoType  string
+	Default string
+	// True is the value of a bool parameter that means true, e.g. "yes".
+	True  string
+	False string
+}
+
+var paramsTemplate = template.Must(template.New("params").Parse(`{{.Header}}
+
+package {{.Package}}
+
+import (
+{{- if .Strconv}}
+	"strconv"
+{{end}}
+	"github.com/Cacsjep/goxis/pkg/acapapp"
+)
+
+// Names of the parameters in the paramConfig of the manifest.
+const (
+{{- range .Params}}
+	Param{{.Ident}} = {{printf "%q" .Name}} // {{.Type}}
+{{- end}}
+)
+
+// Default values of the parameters.
+const (
+{{- range .Params}}
+	Default{{.Ident}} {{.GoType}} = {{.Default}}
+{{- end}}
+)
+
+// Params reads and writes the parameters through the parameter handler of
+// an acapapp.AcapApplication.
+type Params struct {
+	app *acapapp.AcapApplication
+}
+
+// NewParams returns the typed parameters of app.
+func NewParams(app *acapapp.AcapApplication) *Params {
+	return &Params{app: app}
+}
+{{range .Params}}
+// {{.Ident}} returns the value of the {{.Name}} parameter.
+func (p *Params) {{.Ident}}() ({{.GoType}}, error) {
+{{- if eq .GoType "int"}}
+	return p.app.ParamHandler.GetAsInt(Param{{.Ident}})
+{{- else if eq .GoType "float64"}}
+	return p.app.ParamHandler.GetAsFloat(Param{{.Ident}})
+{{- else if eq .GoType "bool"}}
+	v, err := p.app.ParamHandler.Get(Param{{.Ident}})
+	return v == {{printf "%q" .True}}, err
+{{- else}}
+	return p.app.ParamHandler.Get(Param{{.Ident}})
+{{- end}}
+}
+
+// Set{{.Ident}} sets the {{.Name}} parameter.
+func (p *Params) Set{{.Ident}}(v {{.GoType}}) error {
+{{- if eq .GoType "int"}}
+	return p.app.ParamHandler.Set(Param{{.Ident}}, strconv.Itoa(v), true)
+{{- else if eq .GoType "float64"}}
+	return p.app.ParamHandler.Set(Param{{.Ident}}, strconv.FormatFloat(v, 'f', -1, 64), true)
+{{- else if eq .GoType "bool"}}
+	value := {{printf "%q" .False}}
+	if v {
+		value = {{printf "%q" .True}}
+	}
+	return p.app.ParamHandler.Set(Param{{.Ident}}, value, true)
+{{- else}}
+	return p.app.ParamHandler.Set(Param{{.Ident}}, v, true)
+{{- end}}
+}
+{{end}}`))
+
+// GenerateParams returns a Go file of package pkg with constants for the
+// names and defaults of the paramConfig of m and a Params type with typed
+// accessors for the parameter API of goxis. int, float and bool parameters
+// get Go types, all others are strings.
+func GenerateParams(m *axmanifest.ApplicationManifestSchema, pkg string) ([]byte, error) {
+	var params []param
+	idents := make(map[string]string)
+	needStrconv := false
+	for _, item := range m.ACAPPackageConf.Configuration.ParamConfig {
+		p, err := newParam(item)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := idents[p.Ident]; ok {
+			return nil, fmt.Errorf("parameters %s and %s both become %s in Go", other, p.Name, p.Ident)
+		}
+		idents[p.Ident] = p.Name
+		needStrconv = needStrconv || p.GoType == "int" || p.GoType == "float64"
+		params = append(params, p)
+	}
+	if len(params) == 0 {
+		return nil, fmt.Errorf("the manifest has no paramConfig")
+	}
+
+	var buf bytes.Buffer
+	err := paramsTemplate.Execute(&buf, map[string]any{
+		"Header":  paramsHeader,
+		"Package": pkg,
+		"Strconv": needStrconv,
+		"Params":  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// newParam maps a paramConfig entry to its Go identifier, type and default.
+func newParam(item axmanifest.ParamConfigItem) (param, error) {
+	p := param{Name: item.Name, Type: item.Type, Ident: goIdent(item.Name), GoType: "string", Default: strconv.Quote(item.Default)}
+	if p.Ident == "" {
+		return p, fmt.Errorf("parameter %q has no usable Go name", item.Name)
+	}
+	kind, options, _ := strings.Cut(item.Type, ":")
+	switch kind {
+	case "int":
+		if _, err := strconv.Atoi(item.Default); err != nil {
+			return p, fmt.Errorf("default %q of the int parameter %s is not a number", item.Default, item.Name)
+		}
+		p.GoType, p.Default = "int", item.Default
+	case "float":
+		if _, err := strconv.ParseFloat(item.Default, 64); err != nil {
+			return p, fmt.Errorf("default %q of the float parameter %s is not a number", item.Default, item.Name)
+		}
+		p.GoType, p.Default = "float64", item.Default
+	case "bool":
+		p.False, p.True = "no", "yes"
+		if options != "" {
+			values := strings.Split(options, ",")
+			if len(values) != 2 {
+				return p, fmt.Errorf("type %q of the parameter %s needs two values, e.g. bool:no,yes", item.Type, item.Name)
+			}
+			p.False, p.True = values[0], values[1]
+		}
+		if item.Default != p.False && item.Default != p.True {
+			return p, fmt.Errorf("default %q of the bool parameter %s is neither %s nor %s", item.Default, item.Name, p.False, p.True)
+		}
+		p.GoType, p.Default = "bool", strconv.FormatBool(item.Default == p.True)
+	}
+	return p, nil
+}
+
+// goIdent turns a parameter name into an exported Go identifier, e.g.
+// "max_fps" into "MaxFps".
+func goIdent(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	ident := b.String()
+	if ident != "" && unicode.IsDigit(rune(ident[0])) {
+		ident = "P" + ident
+	}
+	return ident
+}
+
+// IsGeneratedParams reports whether the Go file at path was written by
+// 'generate params'.
+func IsGeneratedParams(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	line, _ := bufio.NewReader(f).ReadString('\n')
+	return strings.TrimSpace(line) == paramsHeader
+}
+
+// PackageName returns the package name of the Go files in dir, main if it has none.
+func PackageName(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		return f.Name.Name, nil
+	}
+	return "main", nil
+}
+
+// WriteParams writes src to path unless the file already has that content.
+// The file is replaced in one step, so concurrent builds of the same
+// application never see a partial file. It reports whether path changed.
+func WriteParams(path string, src []byte) (bool, error) {
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, src) {
+		return false, nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".params-*.go.tmp")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(src); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp.Name(), path)
+}
+
+// regenerateParams renders the params files of the application directory
+// from the manifest of bc into the build context, so they never fall behind
+// the paramConfig. The files on the host are left alone: targets with their
+// own manifest are built at the same time from the same directory.
+func (b *Builder) regenerateParams() error {
+	bc := b.Config
+	b.params = nil
+	if len(bc.Manifest.ACAPPackageConf.Configuration.ParamConfig) == 0 {
+		return nil
+	}
+	contextDir, err := bc.contextDir()
+	if err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(contextDir, bc.AppDirectory, "*.go"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !IsGeneratedParams(file) {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return err
+		}
+		src, err := GenerateParams(bc.Manifest, f.Name.Name)
+		if err != nil {
+			return fmt.Errorf("failed to generate %s: %w", file, err)
+		}
+		if current, err := os.ReadFile(file); err == nil && bytes.Equal(current, src) {
+			continue
+		}
+		rel, err := filepath.Rel(contextDir, file)
+		if err != nil {
+			return err
+		}
+		if b.params == nil {
+			b.params = make(map[string][]byte)
+		}
+		b.params[filepath.ToSlash(rel)] = src
+		fmt.Fprintf(b.log, "Regenerated %s from the paramConfig of the manifest, in the build context only\n", file)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package builder
+
+import (
+	"bytes"
+	"flag"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// paramsManifest returns a manifest with the given paramConfig.
+func paramsManifest(t *testing.T, params ...axmanifest.ParamConfigItem) *axmanifest.ApplicationManifestSchema {
+	m := testManifest(t, testManifestJSON)
+	m.ACAPPackageConf.Configuration.ParamConfig = params
+	return m
+}
+
+func TestGoIdent(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Threshold", "Threshold"},
+		{"max_fps", "MaxFps"},
+		{"scale-factor", "ScaleFactor"},
+		{"Stream.Resolution", "StreamResolution"},
+		{"fps2", "Fps2"},
+		{"2nd_stream", "P2ndStream"},
+		{"über grenze", "ÜberGrenze"},
+		{"--", ""},
+	}
+	for _, tt := range tests {
+		if got := goIdent(tt.name); got != tt.want {
+			t.Errorf("goIdent(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateParams(t *testing.T) {
+	m := paramsManifest(t,
+		axmanifest.ParamConfigItem{Name: "Threshold", Type: "int:min=0;max=100", Default: "50"},
+		axmanifest.ParamConfigItem{Name: "scale-factor", Type: "float", Default: "1.5"},
+		axmanifest.ParamConfigItem{Name: "enabled", Type: "bool:no,yes", Default: "yes"},
+		axmanifest.ParamConfigItem{Name: "Overlay", Type: "bool:off,on", Default: "off"},
+		axmanifest.ParamConfigItem{Name: "Mode", Type: "enum:day|night", Default: "day"},
+		axmanifest.ParamConfigItem{Name: "Greeting", Type: "string", Default: "say \"hi\""},
+		axmanifest.ParamConfigItem{Name: "2nd_stream", Type: "string", Default: ""},
+	)
+	src, err := GenerateParams(m, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted, err := format.Source(src); err != nil || !bytes.Equal(formatted, src) {
+		t.Errorf("output is not gofmt formatted: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "params_gen.go.golden")
+	if *update {
+		if err := os.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("output differs from %s, run go test -update to rewrite it:\n%s", golden, src)
+	}
+}
+
+func TestGenerateParamsStrings(t *testing.T) {
+	// Without int and float parameters strconv is not imported
+	src, err := GenerateParams(paramsManifest(t, axmanifest.ParamConfigItem{Name: "Greeting", Type: "string", Default: "hi"}), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), ParamsFile, src, parser.ImportsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Name.Name != "app" || len(f.Imports) != 1 {
+		t.Errorf("package %s with %d imports, want app with only acapapp", f.Name.Name, len(f.Imports))
+	}
+}
+
+func TestGenerateParamsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  []axmanifest.ParamConfigItem
+		wantErr string
+	}{
+		{"no params", nil, "the manifest has no paramConfig"},
+		{
+			"ident collision",
+			[]axmanifest.ParamConfigItem{{Name: "max_fps", Type: "int", Default: "1"}, {Name: "max-fps", Type: "int", Default: "1"}},
+			"parameters max_fps and max-fps both become MaxFps in Go",
+		},
+		{"no Go name", []axmanifest.ParamConfigItem{{Name: "__", Type: "string"}}, `parameter "__" has no usable Go name`},
+		{"int default", []axmanifest.ParamConfigItem{{Name: "Fps", Type: "int", Default: "fast"}}, `default "fast" of the int parameter Fps is not a number`},
+		{"float default", []axmanifest.ParamConfigItem{{Name: "Scale", Type: "float", Default: ""}}, `default "" of the float parameter Scale is not a number`},
+		{"bool values", []axmanifest.ParamConfigItem{{Name: "On", Type: "bool:yes", Default: "yes"}}, "needs two values"},
+		{"bool default", []axmanifest.ParamConfigItem{{Name: "On", Type: "bool:no,yes", Default: "true"}}, `default "true" of the bool parameter On is neither no nor yes`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GenerateParams(paramsManifest(t, tt.params...), "main")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegenerateParams(t *testing.T) {
+	dir := t.TempDir()
+	stale, err := GenerateParams(paramsManifest(t, axmanifest.ParamConfigItem{Name: "Old", Type: "string"}), "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"app/params_gen.go": string(stale),
+		"app/main.go":       "package main\n",
+	})
+	builder := func(params ...axmanifest.ParamConfigItem) *Builder {
+		return New(nil, &BuildConfiguration{Manifest: paramsManifest(t, params...), ContextDir: dir, AppDirectory: "app"}, nil)
+	}
+
+	// Targets with their own manifest build from the same directory at once
+	a := builder(axmanifest.ParamConfigItem{Name: "A", Type: "string"})
+	b := builder(axmanifest.ParamConfigItem{Name: "B", Type: "string"})
+	for _, target := range []*Builder{a, b} {
+		if err := target.regenerateParams(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(string(a.params["app/params_gen.go"]), `ParamA = "A"`) || !strings.Contains(string(b.params["app/params_gen.go"]), `ParamB = "B"`) {
+		t.Errorf("params %v and %v, want each of their own manifest", a.params, b.params)
+	}
+	if current, _ := os.ReadFile(filepath.Join(dir, "app", "params_gen.go")); !bytes.Equal(current, stale) {
+		t.Error("regenerateParams changed the file in the application directory")
+	}
+
+	same := builder(axmanifest.ParamConfigItem{Name: "Old", Type: "string"})
+	none := builder()
+	for _, target := range []*Builder{same, none} {
+		if err := target.regenerateParams(); err != nil || target.params != nil {
+			t.Errorf("params %v, %v, want none", target.params, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package builder
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// defaultManifest is the manifest name acap-build reads.
+const defaultManifest = "manifest.json"
+
+// defaultLdFlags strip the symbol table and debug information.
+const defaultLdFlags = "-s -w"
+
+// libLdFlags let the binary find the shared libraries bundled in ./lib.
+const libLdFlags = "-extldflags '-L./lib -Wl,-rpath,./lib'"
+
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// goBuildCommand returns the shell command that builds the binary of the application.
+// The build info is set with -X before the LdVars of bc, so they can override it.
+func goBuildCommand(bc *BuildConfiguration, appName string, info BuildInfo) (string, error) {
+	var args []string
+	for _, env := range bc.GoEnv {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok || !envNameRegex.MatchString(name) {
+			return "", fmt.Errorf("invalid environment variable %q, expected NAME=value", env)
+		}
+		args = append(args, name+"="+shellQuote(value))
+	}
+
+	args = append(args, "go", "build")
+	if bc.BuildTags != "" {
+		args = append(args, "-tags", shellQuote(bc.BuildTags))
+	}
+	if bc.TrimPath {
+		args = append(args, "-trimpath")
+	}
+	if bc.GcFlags != "" {
+		args = append(args, "-gcflags", shellQuote(bc.GcFlags))
+	}
+
+	ldflags := []string{defaultLdFlags}
+	for _, v := range info.ldVars() {
+		ldflags = append(ldflags, "-X", v)
+	}
+	for _, v := range bc.LdVars {
+		if name, _, ok := strings.Cut(v, "="); !ok || !strings.Contains(name, ".") {
+			return "", fmt.Errorf("invalid -X value %q, expected importpath.name=value", v)
+		}
+		ldflags = append(ldflags, "-X", v)
+	}
+	if bc.LdFlags != "" {
+		ldflags = append(ldflags, bc.LdFlags)
+	}
+	ldflags = append(ldflags, libLdFlags)
+	args = append(args, "-ldflags", shellQuote(strings.Join(ldflags, " ")))
+
+	args = append(args, "-o", shellQuote(appName), ".")
+	return strings.Join(args, " "), nil
+}
+
+// makefile returns the Makefile whose build target runs command.
+func makefile(command string) []byte {
+	return []byte(".PHONY: build\n\nbuild:\n\t" + strings.ReplaceAll(command, "$", "$$") + "\n")
+}
+
+// buildRecipe returns the files generated into the application directory of
+// the build context: the Makefile and, if another manifest was selected or
+// its version or schema changed, the manifest as manifest.json.
+func buildRecipe(bc *BuildConfiguration, info BuildInfo, readFile func(name string) ([]byte, error)) (map[string][]byte, string, error) {
+	command, err := goBuildCommand(bc, bc.Manifest.ACAPPackageConf.Setup.AppName, info)
+	if err != nil {
+		return nil, "", err
+	}
+	files := map[string][]byte{
+		path.Join(bc.AppDirectory, "Makefile"): makefile(command),
+	}
+
+	selected := bc.ManifestPath != "" && path.Clean(bc.ManifestPath) != defaultManifest
+	version := ""
+	if info.Version != bc.Manifest.ACAPPackageConf.Setup.Version {
+		version = info.Version
+	}
+	schemaVersion := ""
+	if bc.SchemaVersion != "" && bc.SchemaVersion != bc.Manifest.SchemaVersion {
+		schemaVersion = bc.SchemaVersion
+	}
+	if selected || version != "" || schemaVersion != "" {
+		manifest, err := readFile(manifestPath(bc))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read manifest: %w", err)
+		}
+		if version != "" || schemaVersion != "" {
+			if manifest, err = stampManifest(manifest, version, schemaVersion); err != nil {
+				return nil, "", err
+			}
+		}
+		files[path.Join(bc.AppDirectory, defaultManifest)] = manifest
+	}
+	return files, command, nil
+}
+
+// manifestPath returns the path of the selected manifest in the build context.
+func manifestPath(bc *BuildConfiguration) string {
+	if bc.ManifestPath == "" {
+		return path.Join(bc.AppDirectory, defaultManifest)
+	}
+	return path.Join(bc.AppDirectory, bc.ManifestPath)
+}
+
+// shellQuote quotes s for sh, unless it only contains safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=,:@+", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,106 @@
+package builder
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoBuildCommand(t *testing.T) {
+	info := BuildInfo{Version: "1.2.3", Commit: "abc123", BuildTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	const infoVars = "-X main.version=1.2.3 -X main.commit=abc123 -X main.dirty=false -X main.buildTime=2024-05-01T12:00:00Z"
+	const lib = " -extldflags '\\''-L./lib -Wl,-rpath,./lib'\\''"
+	tests := []struct {
+		name    string
+		bc      BuildConfiguration
+		want    string
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: "go build -ldflags '-s -w " + infoVars + lib + "' -o app .",
+		},
+		{
+			name: "flags",
+			bc:   BuildConfiguration{BuildTags: "netgo,osusergo", TrimPath: true, GcFlags: "all=-N -l", LdFlags: "-linkmode external"},
+			want: "go build -tags netgo,osusergo -trimpath -gcflags 'all=-N -l' -ldflags '-s -w " + infoVars + " -linkmode external" + lib + "' -o app .",
+		},
+		{
+			name: "X after the build info",
+			bc:   BuildConfiguration{LdVars: []string{"main.version=custom", "example.com/app/config.url=http://localhost"}},
+			want: "go build -ldflags '-s -w " + infoVars + " -X main.version=custom -X example.com/app/config.url=http://localhost" + lib + "' -o app .",
+		},
+		{
+			name: "environment",
+			bc:   BuildConfiguration{GoEnv: []string{"GOAMD64=v3", "CGO_CFLAGS=-O2 -g", "GOFLAGS="}},
+			want: "GOAMD64=v3 CGO_CFLAGS='-O2 -g' GOFLAGS='' go build -ldflags '-s -w " + infoVars + lib + "' -o app .",
+		},
+		{name: "environment without value", bc: BuildConfiguration{GoEnv: []string{"GOAMD64"}}, wantErr: `invalid environment variable "GOAMD64"`},
+		{name: "environment name", bc: BuildConfiguration{GoEnv: []string{"A;rm -rf /=x"}}, wantErr: "invalid environment variable"},
+		{name: "X without value", bc: BuildConfiguration{LdVars: []string{"main.version"}}, wantErr: `invalid -X value "main.version"`},
+		{name: "X without package", bc: BuildConfiguration{LdVars: []string{"version=1"}}, wantErr: `invalid -X value "version=1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := goBuildCommand(&tt.bc, "app", info)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %q, %v, want error %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"app", "app"},
+		{"netgo,osusergo", "netgo,osusergo"},
+		{"main.url=http://host:80/x@y+z", "main.url=http://host:80/x@y+z"},
+		{"", "''"},
+		{"-N -l", "'-N -l'"},
+		{"it's", `'it'\''s'`},
+		{`say "hi"`, `'say "hi"'`},
+		{"$HOME", "'$HOME'"},
+		{"a;b", "'a;b'"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestShellQuoteSh checks that sh reads the quoted strings back unchanged.
+func TestShellQuoteSh(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	for _, s := range []string{"plain", "", "two words", "it's", `"quoted" 'both'`, "$HOME `id` \\n", "line\nbreak"} {
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(s)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != s {
+			t.Errorf("sh read %q as %q", s, out)
+		}
+	}
+}
+
+func TestMakefile(t *testing.T) {
+	got := string(makefile("GOFLAGS='-ldflags=-X=main.v=$1' go build -o app ."))
+	want := ".PHONY: build\n\nbuild:\n\tGOFLAGS='-ldflags=-X=main.v=$$1' go build -o app .\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package builder
+
+import (
+	"io"
+	"net/url"
+	"strings"
+)
+
+// redactWriter replaces every occurrence of a secret before writing to out.
+type redactWriter struct {
+	out     io.Writer
+	secrets []string
+	// pending is the end of the written data that may be the start of a
+	// secret split across writes, it is held back until the next Write or Flush.
+	pending string
+}
+
+// newRedactWriter returns a writer that hides password, also in its URL
+// encoded form, or out itself when there is no password.
+func newRedactWriter(out io.Writer, password string) io.Writer {
+	if password == "" {
+		return out
+	}
+	secrets := []string{password}
+	if escaped := url.QueryEscape(password); escaped != password {
+		secrets = append(secrets, escaped)
+	}
+	return &redactWriter{out: out, secrets: secrets}
+}
+
+func (w *redactWriter) Write(p []byte) (int, error) {
+	s := w.pending + string(p)
+	for _, secret := range w.secrets {
+		s = strings.ReplaceAll(s, secret, "********")
+	}
+	// Hold back the longest end of s that starts a secret, at most len(secret)-1 bytes
+	keep := 0
+	for _, secret := range w.secrets {
+		for n := min(len(secret)-1, len(s)); n > keep; n-- {
+			if strings.HasSuffix(s, secret[:n]) {
+				keep = n
+				break
+			}
+		}
+	}
+	w.pending = s[len(s)-keep:]
+	if _, err := io.WriteString(w.out, s[:len(s)-keep]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the held back end of the data, which turned out not to be a secret.
+func (w *redactWriter) Flush() error {
+	s := w.pending
+	w.pending = ""
+	_, err := io.WriteString(w.out, s)
+	return err
+}
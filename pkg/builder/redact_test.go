@@ -0,0 +1,50 @@
+package builder
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedactWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"one write", []string{"curl -u root:s3cr&t http://cam\n"}, "curl -u root:******** http://cam\n"},
+		{"url encoded", []string{"pwd=s3cr%26t\n"}, "pwd=********\n"},
+		{"split", []string{"password s3", "cr", "&t done\n"}, "password ******** done\n"},
+		{"split url encoded", []string{"pwd=s3cr%", "26t\n"}, "pwd=********\n"},
+		{"prefix only", []string{"s3c", "ret\n"}, "s3cret\n"},
+		{"prefix at the end", []string{"last s3cr"}, "last s3cr"},
+		{"twice", []string{"s3cr&ts3cr", "&t"}, "****************"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := newRedactWriter(&out, "s3cr&t")
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+			if err := w.(*redactWriter).Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if strings.Contains(out.String(), "s3cr&t") {
+				t.Error("the password leaked")
+			}
+		})
+	}
+}
+
+func TestRedactWriterWithoutPassword(t *testing.T) {
+	var out bytes.Buffer
+	if w := newRedactWriter(&out, ""); w != &out {
+		t.Error("newRedactWriter without a password does not return out")
+	}
+}
@@ -0,0 +1,163 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// BuildError is a failure reported by the Docker daemon while building the image.
+type BuildError struct {
+	// Step is the Dockerfile step that failed, e.g. "Step 7/12 : RUN make",
+	// empty when the build failed before the first step.
+	Step    string
+	Message string
+	Code    int
+	// Diagnostics are the errors of the Go toolchain in the build output.
+	Diagnostics []Diagnostic
+}
+
+func (e *BuildError) Error() string {
+	if e.Step == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Step, strings.TrimSpace(e.Message))
+}
+
+// progressStep is the percentage a download or extraction has to advance
+// before its progress bar is printed again.
+const progressStep = 10
+
+// buildStream decodes the JSON messages of an image build, writes them to
+// out and keeps track of the Dockerfile step and the progress of every layer.
+type buildStream struct {
+	out         io.Writer
+	step        string
+	imageID     string
+	diagnostics diagnosticParser
+	// line holds a stream line until its newline arrives
+	line string
+	// progress is the last printed status and percentage of each layer
+	progress map[string]layerProgress
+}
+
+type layerProgress struct {
+	status  string
+	percent int64
+}
+
+// readBuildStream reads the response of an image build until it ends and
+// returns the ID of the built image, if the daemon reported it. A failure
+// reported by the daemon is returned as *BuildError.
+func readBuildStream(body io.Reader, out io.Writer, diagnostics diagnosticParser) (string, error) {
+	s := &buildStream{out: out, diagnostics: diagnostics, progress: make(map[string]layerProgress)}
+	decoder := json.NewDecoder(body)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("unable to decode build response: %w", err)
+		}
+		if err := s.handle(&msg); err != nil {
+			var buildErr *BuildError
+			if errors.As(err, &buildErr) {
+				buildErr.Diagnostics = s.diagnostics.found
+			}
+			return "", err
+		}
+	}
+	s.flushLine()
+	return s.imageID, nil
+}
+
+func (s *buildStream) handle(msg *jsonmessage.JSONMessage) error {
+	if msg.Error != nil || msg.ErrorMessage != "" {
+		s.flushLine()
+	}
+	if msg.Error != nil {
+		return &BuildError{Step: s.step, Message: msg.Error.Message, Code: msg.Error.Code}
+	}
+	if msg.ErrorMessage != "" {
+		return &BuildError{Step: s.step, Message: msg.ErrorMessage}
+	}
+
+	if msg.Aux != nil {
+		var result types.BuildResult
+		if err := json.Unmarshal(*msg.Aux, &result); err == nil && result.ID != "" {
+			s.imageID = result.ID
+		}
+		return nil
+	}
+
+	if msg.Stream != "" {
+		s.line += msg.Stream
+		for {
+			i := strings.IndexByte(s.line, '\n')
+			if i < 0 {
+				break
+			}
+			line := s.line[:i+1]
+			s.line = s.line[i+1:]
+			if err := s.handleLine(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if msg.Status != "" {
+		s.printStatus(msg)
+	}
+	return nil
+}
+
+// handleLine tracks the Dockerfile step and the diagnostics of a line of the
+// build output and prints it, with host paths in diagnostics.
+func (s *buildStream) handleLine(line string) error {
+	if strings.HasPrefix(line, "Step ") {
+		s.step = strings.TrimSpace(line)
+	} else if strings.Contains(line, "acap-build error") {
+		// acap-build may report errors without failing the step
+		return &BuildError{Step: s.step, Message: line}
+	} else if d, ok := s.diagnostics.parse(line); ok && d.File != "" {
+		line = d.String() + "\n"
+	}
+	fmt.Fprint(s.out, line)
+	return nil
+}
+
+// flushLine handles a stream line that has no newline yet.
+func (s *buildStream) flushLine() {
+	if s.line != "" {
+		s.handleLine(s.line + "\n")
+		s.line = ""
+	}
+}
+
+// printStatus prints the status of a layer pull or extraction. Progress bars
+// are only printed again when they advanced by progressStep percent, so the
+// output stays readable in log files and events.
+func (s *buildStream) printStatus(msg *jsonmessage.JSONMessage) {
+	if msg.ID == "" {
+		fmt.Fprintln(s.out, msg.Status)
+		return
+	}
+	var percent int64
+	bar := ""
+	if p := msg.Progress; p != nil && p.Total > 0 {
+		percent = p.Current * 100 / p.Total / progressStep * progressStep
+		bar = " " + p.String()
+	}
+	last, seen := s.progress[msg.ID]
+	if seen && last.status == msg.Status && last.percent == percent {
+		return
+	}
+	s.progress[msg.ID] = layerProgress{status: msg.Status, percent: percent}
+	fmt.Fprintf(s.out, "%s: %s%s\n", msg.ID, msg.Status, bar)
+}
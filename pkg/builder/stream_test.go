@@ -0,0 +1,117 @@
+package builder
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadBuildStream(t *testing.T) {
+	const (
+		step1 = `{"stream":"Step 1/3 : FROM axisecp/acap-native-sdk\n"}`
+		step2 = `{"stream":"Step 2/3 : RUN make\n"}`
+	)
+	tests := []struct {
+		name    string
+		stream  []string
+		wantID  string
+		wantOut string
+		wantErr *BuildError
+	}{
+		{
+			name:    "image id",
+			stream:  []string{step1, `{"stream":" ---> 1a2b3c\n"}`, `{"aux":{"ID":"sha256:1a2b3c"}}`, `{"stream":"Successfully built 1a2b3c\n"}`},
+			wantID:  "sha256:1a2b3c",
+			wantOut: "Step 1/3 : FROM axisecp/acap-native-sdk\n ---> 1a2b3c\nSuccessfully built 1a2b3c\n",
+		},
+		{
+			name:    "lines split across messages",
+			stream:  []string{`{"stream":"Step 1/3 : FR"}`, `{"stream":"OM scratch\nbuil"}`, `{"stream":"ding"}`},
+			wantOut: "Step 1/3 : FROM scratch\nbuilding\n",
+		},
+		{
+			name:    "daemon error",
+			stream:  []string{step1, step2, `{"errorDetail":{"code":2,"message":"make: *** [all] Error 2"},"error":"make: *** [all] Error 2"}`},
+			wantOut: "Step 1/3 : FROM axisecp/acap-native-sdk\nStep 2/3 : RUN make\n",
+			wantErr: &BuildError{Step: "Step 2/3 : RUN make", Message: "make: *** [all] Error 2", Code: 2},
+		},
+		{
+			name:    "error before the first step",
+			stream:  []string{`{"error":"dockerfile parse error line 3"}`},
+			wantErr: &BuildError{Message: "dockerfile parse error line 3"},
+		},
+		{
+			name:    "acap-build error",
+			stream:  []string{step1, step2, `{"stream":"acap-build error: manifest is invalid\n"}`, `{"stream":"Step 3/3 : RUN true\n"}`},
+			wantOut: "Step 1/3 : FROM axisecp/acap-native-sdk\nStep 2/3 : RUN make\n",
+			wantErr: &BuildError{Step: "Step 2/3 : RUN make", Message: "acap-build error: manifest is invalid\n"},
+		},
+		{
+			name: "diagnostics",
+			stream: []string{
+				step2,
+				`{"stream":"# example.com/app\n"}`,
+				`{"stream":"./main.go:7:2: undefined: foo\n"}`,
+				`{"errorDetail":{"code":1,"message":"exit status 1"}}`,
+			},
+			wantOut: "Step 2/3 : RUN make\n# example.com/app\n" + filepath.Join("/ctx", "app", "main.go") + ":7:2: undefined: foo\n",
+			wantErr: &BuildError{Step: "Step 2/3 : RUN make", Message: "exit status 1", Code: 1, Diagnostics: []Diagnostic{
+				{Kind: DiagnosticCompile, Package: "example.com/app", File: filepath.Join("/ctx", "app", "main.go"), Line: 7, Column: 2, Message: "undefined: foo"},
+			}},
+		},
+		{
+			name: "progress",
+			stream: []string{
+				`{"status":"Pulling from axisecp/acap-native-sdk"}`,
+				`{"status":"Downloading","id":"layer","progressDetail":{"current":1,"total":100}}`,
+				`{"status":"Downloading","id":"layer","progressDetail":{"current":5,"total":100}}`,
+				`{"status":"Downloading","id":"layer","progressDetail":{"current":50,"total":100}}`,
+				`{"status":"Pull complete","id":"layer"}`,
+			},
+			wantOut: "Pulling from axisecp/acap-native-sdk\nlayer: Downloading\nlayer: Downloading\nlayer: Pull complete\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			id, err := readBuildStream(strings.NewReader(strings.Join(tt.stream, "\n")), &out, diagnosticParser{contextDir: "/ctx", appDir: "app"})
+			if id != tt.wantID {
+				t.Errorf("image id = %q, want %q", id, tt.wantID)
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("got %v, want success", err)
+				}
+			} else {
+				var buildErr *BuildError
+				if !errors.As(err, &buildErr) {
+					t.Fatalf("got %v, want a BuildError", err)
+				}
+				if !reflect.DeepEqual(buildErr, tt.wantErr) {
+					t.Errorf("got %#v, want %#v", buildErr, tt.wantErr)
+				}
+			}
+			// The progress bars depend on the terminal, only the lines are compared
+			var lines []string
+			for _, line := range strings.SplitAfter(out.String(), "\n") {
+				if i := strings.Index(line, " ["); i >= 0 {
+					line = line[:i] + "\n"
+				}
+				lines = append(lines, line)
+			}
+			if got := strings.Join(lines, ""); got != tt.wantOut {
+				t.Errorf("output\n%s\nwant\n%s", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestReadBuildStreamInvalid(t *testing.T) {
+	_, err := readBuildStream(strings.NewReader(`{"stream":`), &strings.Builder{}, diagnosticParser{})
+	var buildErr *BuildError
+	if err == nil || errors.As(err, &buildErr) {
+		t.Errorf("got %v, want a decode error", err)
+	}
+}
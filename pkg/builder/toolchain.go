@@ -0,0 +1,142 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// defaultGoVersion is the Go toolchain of applications without a go.mod.
+const defaultGoVersion = "1.25.3"
+
+// toolchainDir holds the generated files of the Go toolchain in the build
+// context, see the Dockerfile.
+const toolchainDir = ".goxis-toolchain"
+
+var (
+	goVersionRegex = regexp.MustCompile(`^1\.\d+(\.\d+)?((rc|beta)\d+)?$`)
+	// tarballRegex matches the names of the official linux-amd64 archives, the SDK images are amd64
+	tarballRegex = regexp.MustCompile(`^go(1\.\d+(?:\.\d+)?(?:(?:rc|beta)\d+)?)\.linux-amd64\.tar\.gz$`)
+)
+
+// goToolchain is the Go toolchain installed into the build image.
+type goToolchain struct {
+	Version string
+	// Source tells where the version comes from, e.g. the path of the go.mod.
+	Source string
+	// Tarball is the path of a local toolchain archive, empty to download it.
+	Tarball string
+}
+
+// tarballName returns the name of the official linux-amd64 archive of a Go version.
+func tarballName(version string) string {
+	return "go" + version + ".linux-amd64.tar.gz"
+}
+
+// resolveToolchain returns the Go toolchain of the build: bc.GoVersion if
+// set, otherwise the toolchain or go line of the go.mod next to the
+// application. bc.GoToolchain is a local archive or a directory of archives.
+func resolveToolchain(bc *BuildConfiguration, contextDir string) (goToolchain, error) {
+	var tc goToolchain
+	if bc.GoVersion != "" {
+		tc = goToolchain{Version: strings.TrimPrefix(bc.GoVersion, "go"), Source: "-go"}
+	} else {
+		goMod, err := findGoMod(contextDir, bc.AppDirectory)
+		if err != nil {
+			return tc, err
+		}
+		if goMod == "" {
+			tc = goToolchain{Version: defaultGoVersion, Source: "default"}
+		} else {
+			version, err := goModVersion(goMod)
+			if err != nil {
+				return tc, err
+			}
+			tc = goToolchain{Version: version, Source: goMod}
+		}
+	}
+	if !goVersionRegex.MatchString(tc.Version) {
+		return tc, fmt.Errorf("invalid Go version %q from %s, expected e.g. 1.23.4", tc.Version, tc.Source)
+	}
+
+	if bc.GoToolchain == "" {
+		return tc, nil
+	}
+	fi, err := os.Stat(bc.GoToolchain)
+	if err != nil {
+		return tc, fmt.Errorf("failed to find Go toolchain: %w", err)
+	}
+	if !fi.IsDir() {
+		// The name of an official archive tells its version
+		if m := tarballRegex.FindStringSubmatch(filepath.Base(bc.GoToolchain)); m != nil && m[1] != tc.Version {
+			if bc.GoVersion != "" {
+				return tc, fmt.Errorf("Go toolchain %s does not match -go %s", bc.GoToolchain, bc.GoVersion)
+			}
+			tc.Version, tc.Source = m[1], bc.GoToolchain
+		}
+		tc.Tarball = bc.GoToolchain
+		return tc, nil
+	}
+	tc.Tarball = filepath.Join(bc.GoToolchain, tarballName(tc.Version))
+	if _, err := os.Stat(tc.Tarball); err != nil {
+		return tc, fmt.Errorf("Go %s is not in the toolchain directory %s, download https://go.dev/dl/%s into it: %w", tc.Version, bc.GoToolchain, tarballName(tc.Version), err)
+	}
+	return tc, nil
+}
+
+// findGoMod returns the path of the go.mod of the application, looking from
+// its directory up to the root of the build context, or blank if there is none.
+func findGoMod(contextDir, appDir string) (string, error) {
+	dir := filepath.Join(contextDir, appDir)
+	for {
+		goMod := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(goMod); err == nil {
+			return goMod, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		if rel, err := filepath.Rel(contextDir, dir); err != nil || rel == "." {
+			return "", nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// goModVersion returns the Go version a go.mod requires: its toolchain line,
+// or else its go line.
+func goModVersion(goMod string) (string, error) {
+	data, err := os.ReadFile(goMod)
+	if err != nil {
+		return "", err
+	}
+	f, err := modfile.Parse(goMod, data, nil)
+	if err != nil {
+		return "", err
+	}
+	if f.Toolchain != nil && f.Toolchain.Name != "default" {
+		return strings.TrimPrefix(f.Toolchain.Name, "go"), nil
+	}
+	if f.Go == nil {
+		return defaultGoVersion, nil
+	}
+	return releaseVersion(f.Go.Version), nil
+}
+
+// releaseVersion returns the first release of a language version: since Go
+// 1.21 "go 1.22" means 1.22.0, older releases had no ".0".
+func releaseVersion(version string) string {
+	parts := strings.Split(version, ".")
+	if len(parts) != 2 {
+		return version
+	}
+	if minor, err := strconv.Atoi(parts[1]); err == nil && minor >= 21 {
+		return version + ".0"
+	}
+	return version
+}
@@ -0,0 +1,134 @@
+package builder
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReleaseVersion(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.22", "1.22.0"},
+		{"1.21", "1.21.0"},
+		{"1.20", "1.20"},
+		{"1.19", "1.19"},
+		{"1.22.3", "1.22.3"},
+		{"1.23rc1", "1.23rc1"},
+	}
+	for _, tt := range tests {
+		if got := releaseVersion(tt.in); got != tt.want {
+			t.Errorf("releaseVersion(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGoModVersion(t *testing.T) {
+	tests := []struct {
+		name, goMod, want string
+	}{
+		{"go line", "module example.com/app\n\ngo 1.22\n", "1.22.0"},
+		{"go line with patch", "module example.com/app\n\ngo 1.23.4\n", "1.23.4"},
+		{"old go line", "module example.com/app\n\ngo 1.19\n", "1.19"},
+		{"toolchain", "module example.com/app\n\ngo 1.22\n\ntoolchain go1.23.2\n", "1.23.2"},
+		{"default toolchain", "module example.com/app\n\ngo 1.22.1\n\ntoolchain default\n", "1.22.1"},
+		{"no go line", "module example.com/app\n", defaultGoVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"go.mod": tt.goMod})
+			got, err := goModVersion(filepath.Join(dir, "go.mod"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveToolchain(t *testing.T) {
+	const goMod = "module example.com/app\n\ngo 1.23.4\n"
+	archives := map[string]string{"toolchains/go1.23.4.linux-amd64.tar.gz": "", "toolchains/go1.24.1.linux-amd64.tar.gz": ""}
+	tests := []struct {
+		name      string
+		files     map[string]string
+		appDir    string
+		goVersion string
+		// toolchain is relative to the test directory
+		toolchain   string
+		wantVersion string
+		wantSource  string
+		wantTarball string
+		wantErr     string
+	}{
+		{name: "go directive", files: map[string]string{"app/go.mod": goMod}, appDir: "app", wantVersion: "1.23.4", wantSource: "app/go.mod"},
+		{name: "toolchain directive", files: map[string]string{"app/go.mod": goMod + "\ntoolchain go1.24.1\n"}, appDir: "app", wantVersion: "1.24.1", wantSource: "app/go.mod"},
+		{name: "workspace go.mod", files: map[string]string{"go.mod": goMod, "apps/cam/main.go": ""}, appDir: "apps/cam", wantVersion: "1.23.4", wantSource: "go.mod"},
+		{name: "no go.mod", files: map[string]string{"app/main.go": ""}, appDir: "app", wantVersion: defaultGoVersion, wantSource: "default"},
+		{name: "-go override", files: map[string]string{"app/go.mod": goMod}, appDir: "app", goVersion: "1.24.1", wantVersion: "1.24.1", wantSource: "-go"},
+		{name: "-go with prefix", goVersion: "go1.22.0", wantVersion: "1.22.0", wantSource: "-go"},
+		{name: "invalid -go", goVersion: "latest", wantErr: `invalid Go version "latest" from -go`},
+		{name: "invalid go.mod version", files: map[string]string{"app/go.mod": "module example.com/app\n\ngo 2.0\n"}, appDir: "app", wantErr: `invalid Go version "2.0"`},
+		{
+			name: "toolchain directory", files: mergeFiles(archives, map[string]string{"app/go.mod": goMod}), appDir: "app",
+			toolchain: "toolchains", wantVersion: "1.23.4", wantSource: "app/go.mod", wantTarball: "toolchains/go1.23.4.linux-amd64.tar.gz",
+		},
+		{
+			name: "toolchain directory with -go", files: archives, goVersion: "1.24.1",
+			toolchain: "toolchains", wantVersion: "1.24.1", wantSource: "-go", wantTarball: "toolchains/go1.24.1.linux-amd64.tar.gz",
+		},
+		{name: "version missing in directory", files: archives, goVersion: "1.22.0", toolchain: "toolchains", wantErr: "Go 1.22.0 is not in the toolchain directory"},
+		{
+			name: "archive tells the version", files: mergeFiles(archives, map[string]string{"app/go.mod": goMod}), appDir: "app",
+			toolchain: "toolchains/go1.24.1.linux-amd64.tar.gz", wantVersion: "1.24.1", wantSource: "toolchains/go1.24.1.linux-amd64.tar.gz", wantTarball: "toolchains/go1.24.1.linux-amd64.tar.gz",
+		},
+		{name: "archive does not match -go", files: archives, goVersion: "1.23.4", toolchain: "toolchains/go1.24.1.linux-amd64.tar.gz", wantErr: "does not match -go 1.23.4"},
+		{
+			name: "custom archive", files: map[string]string{"app/go.mod": goMod, "go-custom.tar.gz": ""}, appDir: "app",
+			toolchain: "go-custom.tar.gz", wantVersion: "1.23.4", wantSource: "app/go.mod", wantTarball: "go-custom.tar.gz",
+		},
+		{name: "missing toolchain", goVersion: "1.23.4", toolchain: "missing", wantErr: "failed to find Go toolchain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			local := func(name string) string {
+				if name == "" || name == "default" || name == "-go" {
+					return name
+				}
+				return filepath.Join(dir, filepath.FromSlash(name))
+			}
+			bc := &BuildConfiguration{AppDirectory: tt.appDir, GoVersion: tt.goVersion, GoToolchain: local(tt.toolchain)}
+			tc, err := resolveToolchain(bc, dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %+v, %v, want error %q", tc, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := goToolchain{Version: tt.wantVersion, Source: local(tt.wantSource), Tarball: local(tt.wantTarball)}
+			if tc != want {
+				t.Errorf("got %+v, want %+v", tc, want)
+			}
+		})
+	}
+}
+
+// mergeFiles returns the files of all maps, for writeFiles.
+func mergeFiles(maps ...map[string]string) map[string]string {
+	files := make(map[string]string)
+	for _, m := range maps {
+		for name, content := range m {
+			files[name] = content
+		}
+	}
+	return files
+}
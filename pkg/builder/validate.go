@@ -0,0 +1,137 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxAppNameLength is the longest appName acap-build accepts.
+const maxAppNameLength = 26
+
+var (
+	schemaVersionRegex   = regexp.MustCompile(`^1\.\d+(\.\d+)?$`)
+	appNameRegex         = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	manifestVersionRegex = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+	runModes             = []string{"respawn", "once", "never"}
+)
+
+// ValidSchemaVersion reports whether v has the form of a manifest
+// schemaVersion, e.g. 1.3 or 1.7.0.
+func ValidSchemaVersion(v string) bool {
+	return schemaVersionRegex.MatchString(v)
+}
+
+// ManifestError lists the problems found in a manifest before the build.
+type ManifestError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("%s has %d problem(s):\n  %s", e.Path, len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// ValidateManifest checks the manifest of bc against the rules of acap-build,
+// the files of the application directory and the chosen SDK and target
+// firmware. All problems are returned at once as *ManifestError.
+func ValidateManifest(bc *BuildConfiguration) error {
+	contextDir, err := bc.contextDir()
+	if err != nil {
+		return err
+	}
+	appDir := filepath.Join(contextDir, bc.AppDirectory)
+	m := bc.Manifest
+	setup := m.ACAPPackageConf.Setup
+	var problems []string
+	problemf := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	requireFile := func(name, what string) {
+		if _, err := os.Stat(filepath.Join(appDir, name)); errors.Is(err, os.ErrNotExist) {
+			problemf("%s %s does not exist in the application directory", what, name)
+		}
+	}
+
+	schemaVersion := m.SchemaVersion
+	if bc.SchemaVersion != "" {
+		schemaVersion = bc.SchemaVersion
+	}
+	if schemaVersion == "" {
+		problemf("schemaVersion is missing")
+	} else if !ValidSchemaVersion(schemaVersion) {
+		problemf("schemaVersion %q is invalid, expected e.g. 1.7.0", schemaVersion)
+	} else {
+		problems = append(problems, bc.compat().schemaProblems(bc, schemaVersion)...)
+	}
+
+	switch {
+	case setup.AppName == "":
+		problemf("acapPackageConf.setup.appName is missing")
+	case len(setup.AppName) > maxAppNameLength:
+		problemf("acapPackageConf.setup.appName %q is %d characters long, at most %d are allowed", setup.AppName, len(setup.AppName), maxAppNameLength)
+	case !appNameRegex.MatchString(setup.AppName):
+		problemf("acapPackageConf.setup.appName %q may only contain letters, digits and '_'", setup.AppName)
+	}
+	if setup.Version == "" {
+		problemf("acapPackageConf.setup.version is missing")
+	} else if !manifestVersionRegex.MatchString(setup.Version) {
+		problemf("acapPackageConf.setup.version %q is not a semantic version like 1.2.3", setup.Version)
+	}
+	if setup.Vendor == "" {
+		problemf("acapPackageConf.setup.vendor is missing")
+	}
+	if !slices.Contains(runModes, setup.RunMode) {
+		problemf("acapPackageConf.setup.runMode %q must be one of %s", setup.RunMode, strings.Join(runModes, ", "))
+	}
+	if arch := setup.Architecture; arch != "" && arch != "all" && bc.Arch != "" && arch != bc.Arch {
+		problemf("acapPackageConf.setup.architecture is %s, but the build is for %s", arch, bc.Arch)
+	}
+
+	requireFile("LICENSE", "the license file")
+	conf := m.ACAPPackageConf
+	if page := conf.Configuration.SettingPage; page != "" {
+		requireFile(filepath.Join("html", page), "the settingPage")
+	}
+	if script := conf.Installation.PostInstallScript; script != "" {
+		requireFile(script, "the postInstallScript")
+	}
+	if script := conf.Uninstallation.PreUninstallScript; script != "" {
+		requireFile(script, "the preUninstallScript")
+	}
+	for _, file := range strings.Fields(bc.FilesToAdd) {
+		requireFile(file, "the additional file")
+	}
+
+	seen := make(map[string]bool)
+	for i, param := range conf.Configuration.ParamConfig {
+		if param.Name == "" || param.Type == "" {
+			problemf("acapPackageConf.configuration.paramConfig[%d] needs a name and a type", i)
+		}
+		if seen[param.Name] {
+			problemf("acapPackageConf.configuration.paramConfig has the parameter %s twice", param.Name)
+		}
+		seen[param.Name] = true
+	}
+
+	if len(problems) > 0 {
+		return &ManifestError{Path: manifestPath(bc), Problems: problems}
+	}
+	return nil
+}
+
+// schemaProblems checks that the SDK and the target firmware of bc support a manifest schema.
+func (db *CompatDB) schemaProblems(bc *BuildConfiguration, schemaVersion string) []string {
+	var problems []string
+	if sdk, ok := db.Sdk(bc.Sdk, bc.Version); ok && sdk.Schema != "" && CompareVersions(schemaVersion, sdk.Schema) > 0 {
+		problems = append(problems, fmt.Sprintf("schemaVersion %s is newer than %s, the newest schema of SDK %s, lower it or use a newer SDK", schemaVersion, sdk.Schema, sdk.Version))
+	}
+	if schema, ok := db.Schema(schemaVersion); ok && bc.TargetFirmware != "" && CompareVersions(schema.Firmware, bc.TargetFirmware) > 0 {
+		problems = append(problems, fmt.Sprintf("schemaVersion %s needs AXIS OS %s, but the target firmware is %s", schemaVersion, schema.Firmware, bc.TargetFirmware))
+	}
+	return problems
+}
@@ -0,0 +1,166 @@
+package builder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+)
+
+func TestValidateManifest(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(bc *BuildConfiguration, setup *axmanifest.Setup)
+		remove string
+		want   []string
+	}{
+		{name: "valid"},
+		{name: "valid pre-release", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.Version = "1.2.3-rc.1+build.5" }},
+		{name: "appName missing", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.AppName = "" }, want: []string{"appName is missing"}},
+		{
+			name:   "appName too long",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.AppName = strings.Repeat("a", 27) },
+			want:   []string{"is 27 characters long, at most 26 are allowed"},
+		},
+		{name: "appName of 26 characters", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.AppName = strings.Repeat("a", 26) }},
+		{name: "appName charset", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.AppName = "my-app" }, want: []string{`appName "my-app" may only contain letters, digits and '_'`}},
+		{name: "version missing", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.Version = "" }, want: []string{"version is missing"}},
+		{name: "bad semver", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.Version = "1.2" }, want: []string{`version "1.2" is not a semantic version`}},
+		{name: "vendor missing", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.Vendor = "" }, want: []string{"vendor is missing"}},
+		{name: "runMode", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.RunMode = "always" }, want: []string{`runMode "always" must be one of respawn, once, never`}},
+		{
+			name:   "architecture",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { s.Architecture = "armv7hf" },
+			want:   []string{"architecture is armv7hf, but the build is for aarch64"},
+		},
+		{name: "missing LICENSE", remove: "LICENSE", want: []string{"the license file LICENSE does not exist"}},
+		{
+			name: "settingPage",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) {
+				bc.Manifest.ACAPPackageConf.Configuration.SettingPage = "missing.html"
+			},
+			want: []string{"the settingPage " + filepath.Join("html", "missing.html") + " does not exist"},
+		},
+		{
+			name: "existing settingPage",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) {
+				bc.Manifest.ACAPPackageConf.Configuration.SettingPage = "index.html"
+			},
+		},
+		{
+			name: "install scripts",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) {
+				bc.Manifest.ACAPPackageConf.Installation.PostInstallScript = "postinstall.sh"
+				bc.Manifest.ACAPPackageConf.Uninstallation.PreUninstallScript = "preuninstall.sh"
+			},
+			want: []string{"the postInstallScript postinstall.sh does not exist", "the preUninstallScript preuninstall.sh does not exist"},
+		},
+		{name: "additional files", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { bc.FilesToAdd = "html missing.txt" }, want: []string{"the additional file missing.txt does not exist"}},
+		{
+			name: "duplicate params",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) {
+				bc.Manifest.ACAPPackageConf.Configuration.ParamConfig = []axmanifest.ParamConfigItem{
+					{Name: "Threshold", Type: "int:min=0;max=100", Default: "50"},
+					{Name: "Threshold", Type: "string", Default: ""},
+					{Name: "Untyped", Default: "x"},
+				}
+			},
+			want: []string{"has the parameter Threshold twice", "paramConfig[2] needs a name and a type"},
+		},
+		{name: "schema missing", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { bc.Manifest.SchemaVersion = "" }, want: []string{"schemaVersion is missing"}},
+		{name: "schema invalid", modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { bc.Manifest.SchemaVersion = "2.0" }, want: []string{`schemaVersion "2.0" is invalid`}},
+		{
+			name:   "schema newer than the SDK",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { bc.Version = "1.14" },
+			want:   []string{"schemaVersion 1.8.0 is newer than 1.7.0, the newest schema of SDK 1.14"},
+		},
+		{
+			name:   "schema newer than the firmware",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) { bc.TargetFirmware = "12.2" },
+			want:   []string{"schemaVersion 1.8.0 needs AXIS OS 12.6, but the target firmware is 12.2"},
+		},
+		{
+			name: "lowered schema",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) {
+				bc.TargetFirmware = "12.2"
+				bc.SchemaVersion = "1.7.3"
+			},
+		},
+		{
+			name: "all problems at once",
+			modify: func(bc *BuildConfiguration, s *axmanifest.Setup) {
+				s.AppName, s.Version, s.Vendor = "my app", "one", ""
+			},
+			remove: "LICENSE",
+			want:   []string{"appName", "semantic version", "vendor is missing", "LICENSE"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"app/LICENSE":         "MIT\n",
+				"app/html/index.html": "<html></html>\n",
+				"app/main.go":         "package main\n",
+			})
+			if tt.remove != "" {
+				if err := os.Remove(filepath.Join(dir, "app", tt.remove)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			bc := &BuildConfiguration{
+				Manifest:     testManifest(t, testManifestJSON),
+				ContextDir:   dir,
+				AppDirectory: "app",
+				Arch:         "aarch64",
+				Sdk:          DefaultSdk,
+				Version:      "12.7.0",
+			}
+			bc.Manifest.SchemaVersion = "1.8.0"
+			if tt.modify != nil {
+				tt.modify(bc, &bc.Manifest.ACAPPackageConf.Setup)
+			}
+
+			err := ValidateManifest(bc)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("got %v, want no problems", err)
+				}
+				return
+			}
+			var manifestErr *ManifestError
+			if !errors.As(err, &manifestErr) {
+				t.Fatalf("got %v, want a ManifestError", err)
+			}
+			if len(manifestErr.Problems) != len(tt.want) {
+				t.Errorf("got %d problems, want %d:\n%v", len(manifestErr.Problems), len(tt.want), err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("%v\ndoes not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidSchemaVersion(t *testing.T) {
+	for v, want := range map[string]bool{
+		"1.3":     true,
+		"1.7.0":   true,
+		"1.10.2":  true,
+		"2.0":     false,
+		"1":       false,
+		"1.7.0.1": false,
+		"v1.7.0":  false,
+		"1.7.x":   false,
+		"":        false,
+	} {
+		if got := ValidSchemaVersion(v); got != want {
+			t.Errorf("ValidSchemaVersion(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
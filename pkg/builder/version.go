@@ -0,0 +1,195 @@
+package builder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BuildInfo identifies the sources of a build. It is injected into the
+// binary as main.version, main.commit, main.dirty and main.buildTime.
+type BuildInfo struct {
+	// Version is the manifest version, with the dev build number if any.
+	Version string `json:"version"`
+	// Commit is the git commit of the sources, empty outside of a git repository.
+	Commit    string    `json:"commit,omitempty"`
+	Dirty     bool      `json:"dirty"`
+	BuildTime time.Time `json:"buildTime"`
+}
+
+func (i BuildInfo) String() string {
+	s := "version " + i.Version
+	if i.Commit != "" {
+		s += ", commit " + i.Commit
+	}
+	if i.Dirty {
+		s += " (dirty)"
+	}
+	return s
+}
+
+// ldVars returns the -X assignments of the build info.
+func (i BuildInfo) ldVars() []string {
+	return []string{
+		"main.version=" + i.Version,
+		"main.commit=" + i.Commit,
+		"main.dirty=" + strconv.FormatBool(i.Dirty),
+		"main.buildTime=" + i.BuildTime.UTC().Format(time.RFC3339),
+	}
+}
+
+// ErrDirtyTree is returned by release builds from a git working tree with uncommitted changes.
+var ErrDirtyTree = errors.New("release builds need a clean git working tree")
+
+var buildNumberRegex = regexp.MustCompile(`^[0-9]+$`)
+
+// collectBuildInfo reads the git state of the application in contextDir and
+// applies the dev build number and release mode of bc.
+func collectBuildInfo(bc *BuildConfiguration, contextDir string) (BuildInfo, error) {
+	info := BuildInfo{Version: bc.Manifest.ACAPPackageConf.Setup.Version}
+	if bc.BuildNumber != "" {
+		if bc.Release {
+			return info, errors.New("release builds can not have a dev build number")
+		}
+		if !buildNumberRegex.MatchString(bc.BuildNumber) {
+			return info, fmt.Errorf("build number %q is not a number", bc.BuildNumber)
+		}
+		info.Version = stampVersion(info.Version, bc.BuildNumber)
+	}
+
+	appDir := bc.AppDirectory
+	if appDir == "" {
+		appDir = "."
+	}
+	commit, err := git(contextDir, "rev-parse", "HEAD")
+	if err != nil {
+		// Not a git repository or no git installed
+		if bc.Release {
+			return info, fmt.Errorf("%w: %s is not in a git repository", ErrDirtyTree, appDir)
+		}
+		info.BuildTime = sourceDateEpoch(time.Now())
+		return info, nil
+	}
+	info.Commit = commit
+
+	status, err := git(contextDir, "status", "--porcelain", "--", appDir, ":(exclude)"+bc.OutputDir, ":(exclude)*.eap")
+	if err != nil {
+		return info, err
+	}
+	if status != "" {
+		if bc.Release {
+			return info, fmt.Errorf("%w:\n%s", ErrDirtyTree, status)
+		}
+		info.Dirty = true
+		// A dirty tree is built at the time of its last change, so rebuilds
+		// without further changes still hit the Docker cache
+		info.BuildTime = sourceDateEpoch(changedFilesTime(contextDir, appDir, bc.OutputDir, commitTime(contextDir)))
+		return info, nil
+	}
+
+	// A clean tree is built at its commit time, so rebuilds hit the Docker cache
+	info.BuildTime = sourceDateEpoch(commitTime(contextDir))
+	return info, nil
+}
+
+// commitTime returns the time of the HEAD commit of the repository at dir,
+// or the current time if it can not be read.
+func commitTime(dir string) time.Time {
+	if ct, err := git(dir, "log", "-1", "--format=%ct"); err == nil {
+		if sec, err := strconv.ParseInt(ct, 10, 64); err == nil {
+			return time.Unix(sec, 0)
+		}
+	}
+	return time.Now()
+}
+
+// changedFilesTime returns the newest modification time of the files with
+// uncommitted changes in appDir, but not before since. Deleted files have
+// no time, so a tree with only deletions is built at since.
+func changedFilesTime(dir, appDir, outputDir string, since time.Time) time.Time {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return since
+	}
+	// Not git, its output is trimmed and the first status may start with a space
+	cmd := exec.Command("git", "status", "--porcelain", "-z", "--untracked-files=all", "--", appDir, ":(exclude)"+outputDir, ":(exclude)*.eap")
+	cmd.Dir = dir
+	status, err := cmd.Output()
+	if err != nil {
+		return since
+	}
+	newest := since
+	entries := strings.Split(string(status), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		if entry[0] == 'R' || entry[0] == 'C' {
+			// The original path of a rename or copy follows
+			i++
+		}
+		// The paths of the porcelain format are relative to the repository root
+		if fi, err := os.Stat(filepath.Join(root, filepath.FromSlash(entry[3:]))); err == nil && fi.ModTime().After(newest) {
+			newest = fi.ModTime()
+		}
+	}
+	return newest
+}
+
+// sourceDateEpoch returns the time of $SOURCE_DATE_EPOCH, or fallback if it is not set.
+func sourceDateEpoch(fallback time.Time) time.Time {
+	if sec, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC()
+	}
+	return fallback.UTC()
+}
+
+// git runs git in dir and returns its trimmed output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// stampVersion adds a dev build number to a semantic version, 1.2.3 becomes
+// 1.2.3-dev.42 and 1.2.3-rc1 becomes 1.2.3-rc1.dev.42.
+func stampVersion(version, buildNumber string) string {
+	if strings.Contains(version, "-") {
+		return version + ".dev." + buildNumber
+	}
+	return version + "-dev." + buildNumber
+}
+
+// stampManifest sets the version and schemaVersion of a manifest, blank
+// values are left unchanged. The key order and indentation are kept.
+func stampManifest(data []byte, version, schemaVersion string) ([]byte, error) {
+	doc, err := ParseManifestDoc(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if version != "" {
+		setup := doc.Root.Path("acapPackageConf.setup", false)
+		if setup == nil {
+			return nil, errors.New("manifest has no acapPackageConf.setup")
+		}
+		setup.Set("version", version)
+	}
+	if schemaVersion != "" {
+		doc.Root.Set("schemaVersion", schemaVersion)
+	}
+	return doc.Bytes()
+}
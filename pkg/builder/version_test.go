@@ -0,0 +1,155 @@
+package builder
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStampVersion(t *testing.T) {
+	tests := []struct {
+		version, buildNumber, want string
+	}{
+		{"1.2.3", "42", "1.2.3-dev.42"},
+		{"1.2.3-rc1", "42", "1.2.3-rc1.dev.42"},
+		{"1.2.3-beta.2", "7", "1.2.3-beta.2.dev.7"},
+		{"0.1.0", "0", "0.1.0-dev.0"},
+	}
+	for _, tt := range tests {
+		if got := stampVersion(tt.version, tt.buildNumber); got != tt.want {
+			t.Errorf("stampVersion(%q, %q) = %q, want %q", tt.version, tt.buildNumber, got, tt.want)
+		}
+	}
+}
+
+func TestCollectBuildInfoBuildNumber(t *testing.T) {
+	tests := []struct {
+		name        string
+		buildNumber string
+		release     bool
+		want        string
+		wantErr     string
+	}{
+		{name: "no build number", want: "1.2.3"},
+		{name: "build number", buildNumber: "42", want: "1.2.3-dev.42"},
+		{name: "not a number", buildNumber: "4a", wantErr: `build number "4a" is not a number`},
+		{name: "release", buildNumber: "42", release: true, wantErr: "release builds can not have a dev build number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := &BuildConfiguration{Manifest: testManifest(t, testManifestJSON), BuildNumber: tt.buildNumber, Release: tt.release}
+			info, err := collectBuildInfo(bc, t.TempDir())
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Version != tt.want {
+				t.Errorf("version = %q, want %q", info.Version, tt.want)
+			}
+		})
+	}
+}
+
+// gitRepo creates a git repository with the files of the app in dir/app committed.
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	writeFiles(t, filepath.Join(dir, "app"), files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, out)
+		}
+	}
+	return dir
+}
+
+func TestCollectBuildInfoDirty(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n", "LICENSE": "MIT\n"})
+	bc := &BuildConfiguration{Manifest: testManifest(t, testManifestJSON), AppDirectory: "app", OutputDir: "build"}
+
+	clean, err := collectBuildInfo(bc, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clean.Dirty || clean.Commit == "" {
+		t.Fatalf("clean tree: %+v", clean)
+	}
+
+	// A change made after the commit is built at its modification time
+	changed := clean.BuildTime.Add(time.Hour)
+	writeFiles(t, filepath.Join(dir, "app"), map[string]string{"main.go": "package main\n\nfunc main() {}\n", "sub/new.go": "package sub\n"})
+	for name, mtime := range map[string]time.Time{"main.go": changed, "sub/new.go": changed.Add(-time.Minute)} {
+		if err := os.Chtimes(filepath.Join(dir, "app", name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		dirty, err := collectBuildInfo(bc, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !dirty.Dirty || !dirty.BuildTime.Equal(changed) {
+			t.Errorf("dirty tree: %+v, want build time %s", dirty, changed)
+		}
+	}
+
+	// Deleted files have no time, the commit time is used
+	os.Remove(filepath.Join(dir, "app", "main.go"))
+	os.RemoveAll(filepath.Join(dir, "app", "sub"))
+	deleted, err := collectBuildInfo(bc, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted.Dirty || !deleted.BuildTime.Equal(clean.BuildTime) {
+		t.Errorf("deleted file: %+v, want build time %s", deleted, clean.BuildTime)
+	}
+
+	bc.Release = true
+	if _, err := collectBuildInfo(bc, dir); err == nil || !strings.Contains(err.Error(), "main.go") {
+		t.Errorf("release build of a dirty tree: %v", err)
+	}
+}
+
+func TestStampManifest(t *testing.T) {
+	data := "{\n  \"schemaVersion\": \"1.7.0\",\n  \"acapPackageConf\": {\n    \"setup\": {\n      \"appName\": \"testapp\",\n      \"vendor\": \"Acme\",\n      \"runMode\": \"respawn\",\n      \"version\": \"1.2.3\"\n    }\n  }\n}\n"
+	tests := []struct {
+		name, version, schema, want string
+	}{
+		{"version", "1.2.3-dev.42", "", strings.Replace(data, `"version": "1.2.3"`, `"version": "1.2.3-dev.42"`, 1)},
+		{"schema", "", "1.5.0", strings.Replace(data, `"schemaVersion": "1.7.0"`, `"schemaVersion": "1.5.0"`, 1)},
+		{"unchanged", "", "", data},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stampManifest([]byte(data), tt.version, tt.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := stampManifest([]byte(`{"schemaVersion": "1.7.0"}`), "1.0.0", ""); err == nil {
+		t.Error("manifest without setup: no error")
+	}
+}
@@ -1,16 +1,27 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"path"
-	"reflect"
+	"path/filepath"
 	"regexp"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/Cacsjep/goxis/pkg/axmanifest"
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+	"github.com/erikgeiser/promptkit/selection"
 	"github.com/erikgeiser/promptkit/textinput"
+	"golang.org/x/term"
+)
+
+var (
+	modulePathRegex = regexp.MustCompile(`^[\w.-]+\.[a-z]{2,}(/[\w.-]+)+$`)
+	projectAppRegex = regexp.MustCompile(`^\w+$`)
+	vendorRegex     = regexp.MustCompile(`^[\w /\-\(\),\.!?\&]+$`)
 )
 
 func Input(question, placeholder string, validator func(string) error) string {
@@ -25,44 +36,62 @@ func Input(question, placeholder string, validator func(string) error) string {
 	return name
 }
 
-func ProjectGenUi() *Project {
-	modulePath := Input("Go module path", "github.com/username/repo", func(s string) error {
-		if s == "" {
-			return fmt.Errorf("module path cannot be empty")
-		}
-		validPathRegex := regexp.MustCompile(`^[\w.-]+\.[a-z]{2,}(/[\w.-]+)+$`)
-		if !validPathRegex.MatchString(s) {
-			return fmt.Errorf("invalid Go module path format")
-		}
+// ProjectGenUi validates the values of p and asks for the missing ones. Without
+// a terminal the missing values are an error naming their flags.
+func ProjectGenUi(p *Project, interactive bool) error {
+	fields := []struct {
+		value       *string
+		flag        string
+		question    string
+		placeholder string
+		validate    func(string) error
+	}{
+		{&p.ModuleName, "module", "Go module path", "github.com/username/repo", func(s string) error {
+			if s == "" {
+				return fmt.Errorf("module path cannot be empty")
+			}
+			if !modulePathRegex.MatchString(s) {
+				return fmt.Errorf("invalid Go module path format")
+			}
+			return nil
+		}},
+		{&p.AppName, "appname", "Acap Manifest appname", "myawesomeacap", func(s string) error {
+			if !projectAppRegex.MatchString(s) || len(s) > 26 {
+				return fmt.Errorf("app name must be alphanumeric and up to 26 characters long")
+			}
+			return nil
+		}},
+		{&p.FriendlyName, "friendlyname", "Acap Manifest friendly name", "My Awesome ACAP", func(s string) error {
+			if len(s) == 0 {
+				return fmt.Errorf("friendly name must not be empty")
+			}
+			return nil
+		}},
+		{&p.Vendor, "vendor", "Acap Manifest vendor name", "Company, Inc.", func(s string) error {
+			if !vendorRegex.MatchString(s) {
+				return fmt.Errorf("vendor name contains invalid characters")
+			}
+			return nil
+		}},
+	}
 
-		return nil
-	})
-	appName := Input("Acap Manifest appname", "myawesomeacap", func(s string) error {
-		if !regexp.MustCompile(`^\w+$`).MatchString(s) || len(s) > 26 {
-			return fmt.Errorf("app name must be alphanumeric and up to 26 characters long")
-		}
-		return nil
-	})
-	friendlyName := Input("Acap Manifest friendly name", "My Awesome ACAP", func(s string) error {
-		// Assuming optional but needs to be non-empty if provided
-		if len(s) == 0 {
-			return fmt.Errorf("friendly name must not be empty")
-		}
-		return nil
-	})
-	vendor := Input("Acap Manifest vendor name", "Company, Inc.", func(s string) error {
-		if !regexp.MustCompile(`^[\w /\-\(\),\.!?\&]+$`).MatchString(s) {
-			return fmt.Errorf("vendor name contains invalid characters")
+	var missing []string
+	for _, field := range fields {
+		switch {
+		case *field.value != "":
+			if err := field.validate(*field.value); err != nil {
+				return fmt.Errorf("-%s: %w", field.flag, err)
+			}
+		case interactive:
+			*field.value = Input(field.question, field.placeholder, field.validate)
+		default:
+			missing = append(missing, "-"+field.flag)
 		}
-		return nil
-	})
-
-	return &Project{
-		ModuleName:   modulePath,
-		AppName:      appName,
-		FriendlyName: friendlyName,
-		Vendor:       vendor,
 	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no terminal to ask for the missing values, pass %s", strings.Join(missing, ", "))
+	}
+	return nil
 }
 
 type Project struct {
@@ -72,139 +101,289 @@ type Project struct {
 	Vendor       string
 }
 
-func createNewProject() {
-
-	project := ProjectGenUi()
+// newProjectOptions are the flags of 'new'.
+type newProjectOptions struct {
+	Project
+	template    string
+	templateDir string
+	schema      string
+	license     string
+	dir         string
+	firmware    string
+	compatFile  string
+	goxis       string
+}
 
-	manifest := axmanifest.ApplicationManifestSchema{
-		SchemaVersion: "1.6.0",
-		ACAPPackageConf: axmanifest.ACAPPackageConf{
-			Setup: axmanifest.Setup{
-				AppName:            project.AppName,
-				FriendlyName:       project.FriendlyName,
-				Vendor:             project.Vendor,
-				EmbeddedSdkVersion: "3.0",
-				RunMode:            "never",
-				Version:            "1.0.0",
-				User:               axmanifest.User{Username: "sdk", Group: "sdk"},
-			},
-		},
+// writeNewFile writes content to path unless the file exists, it reports
+// whether it wrote the file.
+func writeNewFile(path string, content []byte) (bool, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
 	}
-
-	err := os.Mkdir(project.AppName, 0755)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return false, err
 	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return false, err
+	}
+	return true, f.Close()
+}
 
-	samplecode := `package main
-
-import (
-	"github.com/Cacsjep/goxis/pkg/acapapp"
-)
+// goCommand runs go with args in dir, its output is part of the error.
+func goCommand(dir string, args ...string) error {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("go %s: %w\n%s", strings.Join(args, " "), err, out)
+	}
+	return nil
+}
 
-func main() {
-	app := acapapp.NewAcapApplication()
-	app.Syslog.Info("Hello from " + app.Manifest.ACAPPackageConf.Setup.AppName + "!")
-}`
-	err = os.WriteFile(path.Join(project.AppName, "main.go"), []byte(samplecode), 0755)
+func createNewProject(opts newProjectOptions) {
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	var dirs []string
+	if opts.templateDir != "" {
+		dirs = append(dirs, opts.templateDir)
+	}
+	templates, err := loadTemplates(dirs...)
 	if err != nil {
-		fmt.Printf("Failed to write main.go: %v\n", err)
-		os.Exit(1)
+		handleError("Failed to load templates", err)
 	}
-
-	gitignore := `
-*.eap`
-	err = os.WriteFile(path.Join(project.AppName, ".gitignore"), []byte(gitignore), 0755)
+	if opts.template == "" && interactive {
+		opts.template = selectTemplate(templates)
+	} else if opts.template == "" {
+		opts.template = defaultTemplate
+	}
+	tmpl, err := findTemplate(templates, opts.template)
 	if err != nil {
-		fmt.Printf("Failed to write .gitignore: %v\n", err)
-		os.Exit(1)
+		handleError("Failed to load template", err)
 	}
 
-	err = os.WriteFile(path.Join(project.AppName, "LICENSE"), []byte("FILLME"), 0755)
+	project := &opts.Project
+	if err := ProjectGenUi(project, interactive); err != nil {
+		handleError("Invalid project", err)
+	}
+	db, err := builder.LoadCompatDB(opts.compatFile)
 	if err != nil {
-		fmt.Printf("Failed to write LICENSE file: %v\n", err)
-		os.Exit(1)
+		handleError("Failed to load compatibility data", err)
 	}
-
-	manContent, err := customMarshal(manifest)
+	target, err := newProjectTarget(db, opts.firmware)
 	if err != nil {
-		fmt.Printf("Failed to marshal manifest: %v\n", err)
-		os.Exit(1)
+		handleError("Unsupported firmware", err)
+	}
+	switch {
+	case opts.schema == "":
+		opts.schema = target.Schema
+	case !builder.ValidSchemaVersion(opts.schema):
+		handleError("Invalid schema version", fmt.Errorf("%q, expected e.g. 1.7.0", opts.schema))
+	case builder.CompareVersions(opts.schema, target.Schema) > 0:
+		handleError("Unsupported schema version", fmt.Errorf("%s is newer than %s, the newest schema of %s", opts.schema, target.Schema, target))
+	}
+	if err := tmpl.checkSchema(opts.schema); err != nil {
+		handleError("Unsupported schema version", err)
+	}
+	if opts.goxis == "" {
+		opts.goxis = target.Goxis
 	}
+	target.Schema, target.Goxis = opts.schema, opts.goxis
+	data := templateData{Project: project, SchemaVersion: opts.schema}
+	data.StaticUser = builder.CompareVersions(data.SchemaVersion, "1.5.0") < 0
+	data.EmbeddedSdk = builder.CompareVersions(data.SchemaVersion, "1.3") < 0
 
-	err = os.WriteFile(path.Join(project.AppName, "manifest.json"), manContent, 0755)
+	if opts.license == "" && interactive {
+		opts.license = selectLicense()
+	} else if opts.license == "" {
+		opts.license = defaultLicense
+	}
+	license, err := licenseText(opts.license, project.Vendor)
 	if err != nil {
-		fmt.Printf("Failed to write manifest.json: %v\n", err)
-		os.Exit(1)
+		handleError("Failed to create the license", err)
 	}
 
-	originalDir, err := os.Getwd()
-	if err != nil {
-		fmt.Printf("Error getting current directory: %v\n", err)
-		os.Exit(1)
+	dir := opts.dir
+	if dir == "" {
+		dir = project.AppName
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		handleError("Failed to create "+dir, err)
 	}
 
-	err = os.Chdir(project.AppName)
-	if err != nil {
-		fmt.Printf("Error changing to project directory: %v\n", err)
-		os.Exit(1)
+	// Files that exist already are kept, so 'new' can fill up an existing directory
+	var kept []string
+	writeFile := func(name string, content []byte) {
+		written, err := writeNewFile(filepath.Join(dir, name), content)
+		if err != nil {
+			handleError("Failed to write "+name, err)
+		}
+		if !written {
+			kept = append(kept, name)
+		}
 	}
+	writeFile(".gitignore", []byte("*.eap\nbuild/\n"))
+	writeFile("LICENSE", license)
+	writeFile(projectConfigFile, target.config())
 
-	err = exec.Command("go", "mod", "init", project.ModuleName).Run()
+	skipped, err := tmpl.render(dir, data)
 	if err != nil {
-		fmt.Printf("Failed to initialize Go module: %v\n", err)
-		os.Exit(1)
+		handleError("Failed to render template "+tmpl.Name, err)
 	}
+	kept = append(kept, skipped...)
 
-	err = exec.Command("go", "get", "github.com/Cacsjep/goxis").Run()
-	if err != nil {
-		fmt.Printf("Failed to get dependencies: %v\n", err)
-		os.Exit(1)
+	if tmpl.Params {
+		manifest, err := axmanifest.LoadManifest(filepath.Join(dir, "manifest.json"))
+		if err != nil {
+			handleError("Failed to load manifest.json", err)
+		}
+		src, err := builder.GenerateParams(manifest, "main")
+		if err != nil {
+			handleError("Failed to generate parameters", err)
+		}
+		paramsPath := filepath.Join(dir, builder.ParamsFile)
+		if _, err := os.Stat(paramsPath); err == nil && !builder.IsGeneratedParams(paramsPath) {
+			kept = append(kept, builder.ParamsFile)
+		} else if _, err := builder.WriteParams(paramsPath, src); err != nil {
+			handleError("Failed to write "+builder.ParamsFile, err)
+		}
 	}
 
-	err = os.Chdir(originalDir)
-	if err != nil {
-		fmt.Printf("Failed to return to original directory: %v\n", err)
-		os.Exit(1)
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); errors.Is(err, os.ErrNotExist) {
+		if err := goCommand(dir, "mod", "init", project.ModuleName); err != nil {
+			handleError("Failed to initialize Go module", err)
+		}
+	} else {
+		kept = append(kept, "go.mod")
+	}
+
+	// Without -goxis the project gets the goxis version of its SDK in the
+	// compatibility data, or else the one the templates are written against
+	if err := goCommand(dir, "get", "github.com/Cacsjep/goxis@"+opts.goxis); err != nil {
+		handleError("Failed to get dependencies", err)
+	}
+	if err := goCommand(dir, "mod", "tidy"); err != nil {
+		handleError("Failed to tidy go.mod", err)
 	}
 
-	fmt.Println("Project created successfully")
-	fmt.Println("To build the project, run 'goxisbuilder -appdir " + project.AppName + "', or 'goxisbuilder' if you are in the project directory.")
+	for _, name := range kept {
+		fmt.Printf("Kept the existing %s\n", name)
+	}
+	fmt.Printf("Project created successfully from the %s template\n", tmpl.Name)
+	fmt.Printf("Targets %s%s%s, pinned in %s, with manifest schema %s and goxis %s\n", Blue, target, Reset, projectConfigFile, opts.schema, opts.goxis)
+	fmt.Println("To build the project, run 'goxisbuilder -appdir " + dir + "', or 'goxisbuilder' if you are in the project directory.")
 	os.Exit(0)
 }
 
-// Custom marshaling that excludes empty fields deeply.
-func customMarshal(v interface{}) ([]byte, error) {
-	rv := reflect.ValueOf(v)
-	if rv.Kind() == reflect.Ptr {
-		rv = rv.Elem()
+// selectTemplate asks for the template of a new project.
+func selectTemplate(templates []*projectTemplate) string {
+	choices := make([]string, len(templates))
+	for i, t := range templates {
+		choices[i] = fmt.Sprintf("%-8s %s", t.Name, t.Description)
+	}
+	sel := selection.New("Project template", choices)
+	choice, err := sel.RunPrompt()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
+	return strings.Fields(choice)[0]
+}
+
+// projectTarget is the SDK, the newest manifest schema and the goxis version
+// a new project is built with, for an AXIS OS version or the default SDK.
+type projectTarget struct {
+	Firmware string
+	Sdk      builder.SdkRelease
+	Schema   string
+	Goxis    string
+}
 
-	// Create a new map to hold non-empty values.
-	result := make(map[string]interface{})
-	for i := 0; i < rv.NumField(); i++ {
-		field := rv.Field(i)
-		fieldType := rv.Type().Field(i)
-		fieldName := fieldType.Name
-		jsonTag := fieldType.Tag.Get("json")
-		if jsonTag != "" {
-			fieldName = jsonTag
+// newProjectTarget picks the target of a new project for firmware, or the
+// default SDK if firmware is blank.
+func newProjectTarget(db *builder.CompatDB, firmware string) (projectTarget, error) {
+	if firmware != "" {
+		rec, err := db.ForFirmware(firmware)
+		if err != nil {
+			return projectTarget{}, err
 		}
+		return projectTarget{Firmware: firmware, Sdk: rec.Sdk, Schema: rec.Schema.Version, Goxis: sdkGoxisVersion(rec.Sdk)}, nil
+	}
+	sdk, ok := db.Sdk(builder.DefaultSdk, builder.DefaultSdkVersion)
+	if !ok {
+		sdk = builder.SdkRelease{Sdk: builder.DefaultSdk, Version: builder.DefaultSdkVersion, Ubuntu: builder.DefaultUbuntuVersion}
+	}
+	if sdk.Ubuntu == "" {
+		sdk.Ubuntu = builder.DefaultUbuntuVersion
+	}
+	t := projectTarget{Sdk: sdk, Schema: sdk.Schema, Goxis: sdkGoxisVersion(sdk)}
+	if t.Schema == "" {
+		t.Schema = builder.DefaultSchemaVersion
+	}
+	return t, nil
+}
+
+// sdkGoxisVersion returns the goxis version of projects built with sdk, the
+// one goxisbuilder was built with unless the compatibility data names one.
+func sdkGoxisVersion(sdk builder.SdkRelease) string {
+	if sdk.Goxis != "" {
+		return sdk.Goxis
+	}
+	return goxisVersion()
+}
 
-		// Check for zero values, including nested structures.
-		if field.Kind() == reflect.Struct {
-			innerJSON, err := customMarshal(field.Interface())
-			if err != nil {
-				return nil, err
+func (t projectTarget) String() string {
+	s := fmt.Sprintf("%s %s (Ubuntu %s)", t.Sdk.Sdk, t.Sdk.Version, t.Sdk.Ubuntu)
+	if t.Firmware != "" {
+		s = "AXIS OS " + t.Firmware + " with " + s
+	}
+	return s
+}
+
+// config returns the goxis.yaml of a new project, which keeps its builds on
+// the SDK and firmware it was created for. The schema and goxis version are
+// no build flags, they are pinned by manifest.json and go.mod and only
+// recorded as a comment.
+func (t projectTarget) config() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Written by 'goxisbuilder new', flags override these values.\n")
+	fmt.Fprintf(&b, "# Created with manifest schema %s (manifest.json) and goxis %s (go.mod).\n", t.Schema, t.Goxis)
+	fmt.Fprintf(&b, "sdk: %q\n", t.Sdk.Version)
+	fmt.Fprintf(&b, "ubuntu: %q\n", t.Sdk.Ubuntu)
+	if t.Firmware != "" {
+		fmt.Fprintf(&b, "target-firmware: %q\n", t.Firmware)
+	}
+	return []byte(b.String())
+}
+
+// defaultSchemaVersion is the newest manifest schema of the default SDK.
+func defaultSchemaVersion() string {
+	t, _ := newProjectTarget(builder.DefaultCompatDB(), "")
+	return t.Schema
+}
+
+// goxisVersion returns the version of goxis goxisbuilder was built with,
+// latest if it is unknown.
+func goxisVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path != "github.com/Cacsjep/goxis" {
+				continue
 			}
-			if len(innerJSON) > 2 { // Non-empty JSON object.
-				result[fieldName] = json.RawMessage(innerJSON)
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
 			}
-		} else if !field.IsZero() {
-			result[fieldName] = field.Interface()
+			return dep.Version
 		}
 	}
+	return "latest"
+}
 
-	return json.MarshalIndent(result, "", "    ")
+// printTemplates lists the project templates.
+func printTemplates(templates []*projectTemplate) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEMPLATE\tSOURCE\tDESCRIPTION")
+	for _, t := range templates {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Name, t.Source, t.Description)
+	}
+	tw.Flush()
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNewProjectTarget(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	compat := filepath.Join(t.TempDir(), "compat.yaml")
+	err := os.WriteFile(compat, []byte(`sdks:
+  - {sdk: acap-native-sdk, version: "1.15", firmware: "11.11", until: "11.11", ubuntu: "22.04", schema: "1.7.0", goxis: v0.1.0}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := builder.LoadCompatDB(compat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		firmware     string
+		wantSdk      string
+		wantUbuntu   string
+		wantSchema   string
+		wantGoxis    string
+		wantFirmware bool // whether goxis.yaml pins target-firmware
+	}{
+		{"", builder.DefaultSdkVersion, builder.DefaultUbuntuVersion, "1.8.0", goxisVersion(), false},
+		{"11.11", "1.15", "22.04", "1.7.0", "v0.1.0", true},
+		{"11.10", "1.14", "22.04", "1.7.0", goxisVersion(), true},
+	}
+	for _, tt := range tests {
+		t.Run("firmware "+tt.firmware, func(t *testing.T) {
+			target, err := newProjectTarget(db, tt.firmware)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if target.Sdk.Sdk != builder.DefaultSdk || target.Sdk.Version != tt.wantSdk || target.Sdk.Ubuntu != tt.wantUbuntu {
+				t.Errorf("SDK %+v, want %s %s on Ubuntu %s", target.Sdk, builder.DefaultSdk, tt.wantSdk, tt.wantUbuntu)
+			}
+			if target.Schema != tt.wantSchema || target.Goxis != tt.wantGoxis {
+				t.Errorf("schema %s and goxis %s, want %s and %s", target.Schema, target.Goxis, tt.wantSchema, tt.wantGoxis)
+			}
+
+			config := target.config()
+			var values map[string]string
+			if err := yaml.Unmarshal(config, &values); err != nil {
+				t.Fatal(err)
+			}
+			for key := range values {
+				if alias, ok := projectConfigAliases[key]; ok {
+					key = alias
+				}
+				if !isProjectConfigKey(key) {
+					t.Errorf("config key %s is no flag of build", key)
+				}
+			}
+			if values["sdk"] != tt.wantSdk || values["ubuntu"] != tt.wantUbuntu {
+				t.Errorf("config %v, want sdk %s and ubuntu %s", values, tt.wantSdk, tt.wantUbuntu)
+			}
+			if _, ok := values["target-firmware"]; ok != tt.wantFirmware || ok && values["target-firmware"] != tt.firmware {
+				t.Errorf("config %v, want target-firmware %q", values, tt.firmware)
+			}
+			if want := "manifest schema " + tt.wantSchema + " (manifest.json) and goxis " + tt.wantGoxis + " (go.mod)"; !strings.Contains(string(config), want) {
+				t.Errorf("config does not record %q:\n%s", want, config)
+			}
+		})
+	}
+
+	if _, err := newProjectTarget(db, "9.0"); err == nil {
+		t.Error("newProjectTarget(9.0) succeeded, want an unsupported firmware")
+	}
+}
+
+func TestNewProjectTargetUnknownSdk(t *testing.T) {
+	target, err := newProjectTarget(&builder.CompatDB{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Sdk.Sdk != builder.DefaultSdk || target.Sdk.Version != builder.DefaultSdkVersion || target.Sdk.Ubuntu != builder.DefaultUbuntuVersion {
+		t.Errorf("SDK %+v, want the default one", target.Sdk)
+	}
+	if target.Schema != builder.DefaultSchemaVersion || target.Goxis != goxisVersion() {
+		t.Errorf("schema %s and goxis %s, want %s and %s", target.Schema, target.Goxis, builder.DefaultSchemaVersion, goxisVersion())
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+	"gopkg.in/yaml.v3"
+)
+
+// buildTarget is one entry of the 'targets' build matrix in goxis.yaml.
+// Empty fields keep the value of the flags or top level keys.
+type buildTarget struct {
+	Name     string     `yaml:"name"`
+	Sdk      string     `yaml:"sdk"`
+	Ubuntu   string     `yaml:"ubuntu"`
+	Firmware string     `yaml:"firmware"`
+	Manifest string     `yaml:"manifest"`
+	Tags     stringList `yaml:"tags"`
+}
+
+// stringList accepts a YAML list or a space- or comma-separated string.
+type stringList string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var items []string
+		if err := value.Decode(&items); err != nil {
+			return err
+		}
+		*l = stringList(strings.Join(items, " "))
+		return nil
+	}
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	*l = stringList(s)
+	return nil
+}
+
+var targetNameRegex = regexp.MustCompile(`^[\w.-]+$`)
+
+// loadBuildTargets reads the build matrix of goxis.yaml in appDir. If names
+// is not empty, only the targets listed in it (space- or comma-separated)
+// are returned.
+func loadBuildTargets(appDir string, names string) ([]buildTarget, error) {
+	data, configPath, err := readProjectConfig(appDir)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Targets []buildTarget `yaml:"targets"`
+	}
+	if data != nil {
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse targets of %s: %w", configPath, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := range config.Targets {
+		target := &config.Targets[i]
+		if target.Name == "" {
+			target.Name = fmt.Sprintf("target%d", i+1)
+		}
+		if !targetNameRegex.MatchString(target.Name) {
+			return nil, fmt.Errorf("%s: target name %q may only contain letters, digits, '_', '.' and '-'", configPath, target.Name)
+		}
+		if seen[target.Name] {
+			return nil, fmt.Errorf("%s: duplicate target name %q", configPath, target.Name)
+		}
+		seen[target.Name] = true
+	}
+
+	selected := strings.Fields(strings.ReplaceAll(names, ",", " "))
+	if len(selected) == 0 {
+		return config.Targets, nil
+	}
+	var targets []buildTarget
+	for _, name := range selected {
+		if !seen[name] {
+			return nil, fmt.Errorf("target %q is not defined in %s", name, configPath)
+		}
+	}
+	for _, target := range config.Targets {
+		if slices.Contains(selected, target.Name) {
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// apply overrides the SDK, Ubuntu version, target firmware, manifest and
+// tags of bc with the values set in the target.
+func (t buildTarget) apply(bc *builder.BuildConfiguration) error {
+	bc.Target = t.Name
+	if t.Sdk != "" {
+		bc.SdkVersion = t.Sdk
+	}
+	if t.Ubuntu != "" {
+		bc.UbunutVersion = t.Ubuntu
+	}
+	if t.Firmware != "" {
+		bc.TargetFirmware = t.Firmware
+	}
+	if t.Tags != "" {
+		bc.BuildTags = builder.NormalizeGoBuildTags(string(t.Tags))
+	}
+	if t.Manifest != "" {
+		manifestPathFull := path.Join(bc.AppDirectory, t.Manifest)
+		amf, err := axmanifest.LoadManifest(manifestPathFull)
+		if err != nil {
+			return fmt.Errorf("failed to load manifest of target %s from %s: %w", t.Name, manifestPathFull, err)
+		}
+		bc.Manifest = amf
+		bc.ManifestPath = t.Manifest
+	}
+	return nil
+}
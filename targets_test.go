@@ -0,0 +1,114 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+func TestLoadBuildTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string // blank for no goxis.yaml
+		names   string
+		want    []buildTarget
+		wantErr string
+	}{
+		{name: "no config"},
+		{name: "no targets", config: "arch: aarch64\n"},
+		{
+			name:   "targets",
+			config: "targets:\n  - {name: axis11, sdk: \"1.15\", firmware: \"11.11\", tags: [a, b]}\n  - {ubuntu: \"24.04\", manifest: m.json, tags: \"c,d\"}\n",
+			want: []buildTarget{
+				{Name: "axis11", Sdk: "1.15", Firmware: "11.11", Tags: "a b"},
+				{Name: "target2", Ubuntu: "24.04", Manifest: "m.json", Tags: "c,d"},
+			},
+		},
+		{
+			name:   "selected",
+			config: "targets:\n  - name: a\n  - name: b.1\n  - name: c_2\n",
+			names:  "c_2, a",
+			want:   []buildTarget{{Name: "a"}, {Name: "c_2"}},
+		},
+		{name: "unknown selected", config: "targets:\n  - name: a\n", names: "b", wantErr: `target "b" is not defined`},
+		{name: "name with a space", config: "targets:\n  - name: axis 12\n", wantErr: `target name "axis 12" may only contain`},
+		{name: "name with a slash", config: "targets:\n  - name: axis/12\n", wantErr: `target name "axis/12" may only contain`},
+		{name: "duplicate", config: "targets:\n  - name: a\n  - name: a\n", wantErr: `duplicate target name "a"`},
+		{name: "duplicate default name", config: "targets:\n  - name: target2\n  - sdk: \"1.15\"\n", wantErr: `duplicate target name "target2"`},
+		{name: "invalid", config: "targets: {name: a}\n", wantErr: "failed to parse targets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.config != "" {
+				writeTestFiles(t, dir, map[string]string{projectConfigFile: tt.config})
+			}
+			targets, err := loadBuildTargets(dir, tt.names)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(targets) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", targets, tt.want)
+			}
+			for i := range targets {
+				if targets[i] != tt.want[i] {
+					t.Errorf("target %d = %+v, want %+v", i, targets[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildTargetApply(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"manifest.json":     testAppManifest,
+		"manifest.v11.json": strings.Replace(testAppManifest, `"1.7.0"`, `"1.3"`, 1),
+	})
+	manifest := &axmanifest.ApplicationManifestSchema{SchemaVersion: "1.7.0"}
+	base := func() *builder.BuildConfiguration {
+		return &builder.BuildConfiguration{
+			AppDirectory:  dir,
+			ManifestPath:  "manifest.json",
+			Manifest:      manifest,
+			SdkVersion:    "12.7.0",
+			UbunutVersion: "24.04",
+			BuildTags:     "base",
+		}
+	}
+
+	bc := base()
+	if err := (buildTarget{Name: "empty"}).apply(bc); err != nil {
+		t.Fatal(err)
+	}
+	if bc.Target != "empty" || bc.SdkVersion != "12.7.0" || bc.UbunutVersion != "24.04" || bc.BuildTags != "base" || bc.Manifest != manifest || bc.ManifestPath != "manifest.json" {
+		t.Errorf("an empty target changed %+v", bc)
+	}
+
+	bc = base()
+	target := buildTarget{Name: "axis11", Sdk: "1.15", Ubuntu: "22.04", Firmware: "11.11", Manifest: "manifest.v11.json", Tags: "a b"}
+	if err := target.apply(bc); err != nil {
+		t.Fatal(err)
+	}
+	if bc.SdkVersion != "1.15" || bc.UbunutVersion != "22.04" || bc.TargetFirmware != "11.11" || bc.BuildTags != "a,b" {
+		t.Errorf("target not applied: %+v", bc)
+	}
+	if bc.ManifestPath != "manifest.v11.json" || bc.Manifest == manifest || bc.Manifest.SchemaVersion != "1.3" {
+		t.Errorf("manifest %s with schema %s, want the one of the target", bc.ManifestPath, bc.Manifest.SchemaVersion)
+	}
+
+	bc = base()
+	err := (buildTarget{Name: "missing", Manifest: "manifest.v9.json"}).apply(bc)
+	if err == nil || !strings.Contains(err.Error(), filepath.ToSlash(filepath.Join(dir, "manifest.v9.json"))) {
+		t.Errorf("missing manifest: %v", err)
+	}
+}
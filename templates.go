@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+	"gopkg.in/yaml.v3"
+)
+
+// builtinTemplates are the project templates of 'new', one directory each.
+//
+//go:embed templates
+var builtinTemplates embed.FS
+
+// templateInfoFile describes a template, its directory holds the files of the project.
+const templateInfoFile = "template.yaml"
+
+// defaultTemplate is the template of 'new' without -template.
+const defaultTemplate = "minimal"
+
+// setupPartial renders acapPackageConf.setup, manifest.json.tmpl of every
+// template includes it with {{template "setup" .}}.
+const setupPartial = `{{define "setup"}}"setup": {
+            "appName": {{json .AppName}},
+            "friendlyName": {{json .FriendlyName}},
+            "vendor": {{json .Vendor}},{{if .EmbeddedSdk}}
+            "embeddedSdkVersion": "3.0",{{end}}
+            "runMode": "respawn",
+            "version": "1.0.0"{{if .StaticUser}},
+            "user": {
+                "username": "sdk",
+                "group": "sdk"
+            }{{end}}
+        }{{end}}`
+
+// projectTemplate is a directory of files rendered into a new project. Files
+// ending in .tmpl are text/template templates of templateData and lose the
+// suffix, all others are copied.
+type projectTemplate struct {
+	Name        string `yaml:"-"`
+	Source      string `yaml:"-"`
+	fsys        fs.FS
+	Description string `yaml:"description"`
+	// Schema is the oldest manifest schema the template works with.
+	Schema string `yaml:"schema"`
+	// Params generates params_gen.go from the paramConfig of the manifest.
+	Params bool `yaml:"params"`
+}
+
+// templateData is what the files of a template are rendered with.
+type templateData struct {
+	*Project
+	SchemaVersion string
+	// StaticUser is set for schemas before 1.5.0, which need setup.user.
+	StaticUser bool
+	// EmbeddedSdk is set for schemas before 1.3, which need setup.embeddedSdkVersion.
+	EmbeddedSdk bool
+}
+
+// userTemplateDir is the directory of the user's own templates.
+func userTemplateDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "goxisbuilder", "templates")
+}
+
+// loadTemplates returns the templates of dirs, the user template directory
+// and the built-in ones. A template of an earlier directory hides those with
+// the same name in later ones.
+func loadTemplates(dirs ...string) ([]*projectTemplate, error) {
+	builtin, err := fs.Sub(builtinTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	sources := []string{}
+	sourceFS := map[string]fs.FS{"built-in": builtin}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+		sources = append(sources, dir)
+		sourceFS[dir] = os.DirFS(dir)
+	}
+	if dir := userTemplateDir(); dir != "" {
+		if _, err := os.Stat(dir); err == nil {
+			sources = append(sources, dir)
+			sourceFS[dir] = os.DirFS(dir)
+		}
+	}
+	sources = append(sources, "built-in")
+
+	seen := make(map[string]bool)
+	var templates []*projectTemplate
+	for _, source := range sources {
+		entries, err := fs.ReadDir(sourceFS[source], ".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read templates from %s: %w", source, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || seen[entry.Name()] {
+				continue
+			}
+			fsys, err := fs.Sub(sourceFS[source], entry.Name())
+			if err != nil {
+				return nil, err
+			}
+			t, err := readTemplate(entry.Name(), source, fsys)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // Not a template
+			}
+			if err != nil {
+				return nil, err
+			}
+			seen[t.Name] = true
+			templates = append(templates, t)
+		}
+	}
+	sort.SliceStable(templates, func(i, j int) bool {
+		return templates[i].Name == defaultTemplate && templates[j].Name != defaultTemplate
+	})
+	return templates, nil
+}
+
+// readTemplate reads the template.yaml of the template in fsys.
+func readTemplate(name, source string, fsys fs.FS) (*projectTemplate, error) {
+	data, err := fs.ReadFile(fsys, templateInfoFile)
+	if err != nil {
+		return nil, err
+	}
+	t := &projectTemplate{Name: name, Source: source, fsys: fsys}
+	if err := yaml.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("failed to parse %s of template %s: %w", templateInfoFile, name, err)
+	}
+	return t, nil
+}
+
+// findTemplate returns the template called name, or the template in the
+// directory name.
+func findTemplate(templates []*projectTemplate, name string) (*projectTemplate, error) {
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		return readTemplate(filepath.Base(name), name, os.DirFS(name))
+	}
+	names := make([]string, len(templates))
+	for i, t := range templates {
+		names[i] = t.Name
+	}
+	return nil, fmt.Errorf("unknown template %q, available are %s", name, strings.Join(names, ", "))
+}
+
+// checkSchema fails if t does not work with the manifest schema version.
+func (t *projectTemplate) checkSchema(version string) error {
+	if t.Schema != "" && builder.CompareVersions(version, t.Schema) < 0 {
+		return fmt.Errorf("template %s needs manifest schema %s or newer, the project uses %s", t.Name, t.Schema, version)
+	}
+	return nil
+}
+
+// render writes the files of t for data into dir. Files that exist already
+// are kept, render returns their names.
+func (t *projectTemplate) render(dir string, data templateData) ([]string, error) {
+	if err := t.checkSchema(data.SchemaVersion); err != nil {
+		return nil, err
+	}
+	var kept []string
+	err := fs.WalkDir(t.fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || name == "." || name == templateInfoFile {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		content, err := fs.ReadFile(t.fsys, name)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(name, ".tmpl") {
+			target = strings.TrimSuffix(target, ".tmpl")
+			if content, err = renderTemplateFile(name, content, data); err != nil {
+				return err
+			}
+		}
+		if path.Base(name) == "manifest.json.tmpl" {
+			var check axmanifest.ApplicationManifestSchema
+			if err := json.Unmarshal(content, &check); err != nil {
+				return fmt.Errorf("template %s renders an invalid manifest.json: %w", t.Name, err)
+			}
+		}
+		written, err := writeNewFile(target, content)
+		if err == nil && !written {
+			kept = append(kept, strings.TrimSuffix(name, ".tmpl"))
+		}
+		return err
+	})
+	return kept, err
+}
+
+func renderTemplateFile(name string, content []byte, data templateData) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			err := enc.Encode(v)
+			return strings.TrimSuffix(buf.String(), "\n"), err
+		},
+	}).Parse(setupPartial)
+	if err == nil {
+		tmpl, err = tmpl.Parse(string(content))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cacsjep/goxis/pkg/axmanifest"
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+// TestBuiltinTemplates renders every built-in template at the oldest and the
+// default schema it supports, validates the result like a build does and
+// type-checks the application against the goxis version 'new' pins.
+func TestBuiltinTemplates(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	templates, err := loadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) == 0 || templates[0].Name != defaultTemplate {
+		t.Fatalf("templates %v, want %s first", templates, defaultTemplate)
+	}
+	oldest := builder.DefaultCompatDB().Schemas[0].Version
+	imp := newGoxisImporter(t)
+	for _, tmpl := range templates {
+		if tmpl.Source != "built-in" {
+			t.Errorf("template %s from %s", tmpl.Name, tmpl.Source)
+		}
+		minimum := tmpl.Schema
+		if minimum == "" {
+			minimum = oldest
+		}
+		for _, schema := range []string{minimum, defaultSchemaVersion()} {
+			t.Run(tmpl.Name+"@"+schema, func(t *testing.T) {
+				dir := t.TempDir()
+				appDir := filepath.Join(dir, "app")
+				if err := os.MkdirAll(appDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				data := templateData{
+					Project:       &Project{ModuleName: "example.com/app", AppName: "test_app", FriendlyName: "Test \"App\"", Vendor: "Acme & Sons"},
+					SchemaVersion: schema,
+					StaticUser:    builder.CompareVersions(schema, "1.5.0") < 0,
+					EmbeddedSdk:   builder.CompareVersions(schema, "1.3") < 0,
+				}
+				if kept, err := tmpl.render(appDir, data); err != nil || len(kept) > 0 {
+					t.Fatalf("render: kept %v, %v", kept, err)
+				}
+				if err := os.WriteFile(filepath.Join(appDir, "LICENSE"), []byte("MIT\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				manifest, err := axmanifest.LoadManifest(filepath.Join(appDir, "manifest.json"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if manifest.SchemaVersion != schema {
+					t.Errorf("schemaVersion = %s, want %s", manifest.SchemaVersion, schema)
+				}
+				bc := &builder.BuildConfiguration{Manifest: manifest, ContextDir: dir, AppDirectory: "app", Arch: "aarch64"}
+				if err := builder.ValidateManifest(bc); err != nil {
+					t.Error(err)
+				}
+				if tmpl.Params {
+					src, err := builder.GenerateParams(manifest, "main")
+					if err != nil {
+						t.Fatalf("generate params: %v", err)
+					}
+					if err := os.WriteFile(filepath.Join(appDir, builder.ParamsFile), src, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				for _, err := range typeCheck(imp, appDir) {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+func TestTemplateSchemaCheck(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	templates, err := loadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range templates {
+		if tmpl.Schema == "" {
+			continue
+		}
+		_, err := tmpl.render(t.TempDir(), templateData{Project: &Project{}, SchemaVersion: "1.3"})
+		if err == nil || !strings.Contains(err.Error(), "needs manifest schema "+tmpl.Schema) {
+			t.Errorf("template %s at schema 1.3: %v", tmpl.Name, err)
+		}
+	}
+}
+
+// goxisModule is the module path of goxis.
+const goxisModule = "github.com/Cacsjep/goxis"
+
+// goxisImporter imports the goxis packages from their source in the module
+// cache and the others from the export data of the host Go. The C parts of
+// goxis are faked, so its Go API is checked, not its bindings.
+type goxisImporter struct {
+	fset *token.FileSet
+	dir  string
+	std  types.Importer
+	pkgs map[string]*types.Package
+}
+
+// newGoxisImporter returns an importer for the goxis version goxisbuilder
+// requires, the one goxisVersion reports.
+func newGoxisImporter(t *testing.T) *goxisImporter {
+	t.Helper()
+	return &goxisImporter{fset: token.NewFileSet(), dir: goxisDir(t), std: importer.Default(), pkgs: make(map[string]*types.Package)}
+}
+
+// goxisDir returns the directory of the goxis version goxisbuilder requires
+// in the module cache, the test is skipped without it.
+func goxisDir(t *testing.T) string {
+	t.Helper()
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", goxisModule).Output()
+	dir := strings.TrimSpace(string(out))
+	if err != nil || dir == "" {
+		t.Skipf("goxis is not in the module cache: %v", err)
+	}
+	return dir
+}
+
+func (imp *goxisImporter) Import(path string) (*types.Package, error) {
+	rel, ok := strings.CutPrefix(path, goxisModule+"/")
+	if !ok {
+		return imp.std.Import(path)
+	}
+	if pkg, ok := imp.pkgs[path]; ok {
+		return pkg, nil
+	}
+	ctxt := build.Default
+	ctxt.GOOS, ctxt.GOARCH, ctxt.CgoEnabled = "linux", "arm64", true
+	bp, err := ctxt.ImportDir(filepath.Join(imp.dir, filepath.FromSlash(rel)), 0)
+	if err != nil {
+		return nil, err
+	}
+	files, err := parseGoFiles(imp.fset, bp.Dir, append(bp.GoFiles, bp.CgoFiles...))
+	if err != nil {
+		return nil, err
+	}
+	// Errors of goxis itself are the C types FakeImportC leaves invalid
+	conf := types.Config{Importer: imp, FakeImportC: true, Error: func(error) {}}
+	pkg, _ := conf.Check(path, imp.fset, files, nil)
+	imp.pkgs[path] = pkg
+	return pkg, nil
+}
+
+// parseGoFiles parses the files names in dir.
+func parseGoFiles(fset *token.FileSet, dir string, names []string) ([]*ast.File, error) {
+	files := make([]*ast.File, 0, len(names))
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.AllErrors|parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// typeCheck returns the syntax and type errors of the main package in dir.
+func typeCheck(imp *goxisImporter, dir string) []error {
+	names, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	if len(names) == 0 {
+		return []error{errors.New("no Go files")}
+	}
+	for i, name := range names {
+		names[i] = filepath.Base(name)
+	}
+	files, err := parseGoFiles(imp.fset, dir, names)
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	conf := types.Config{Importer: imp, Error: func(err error) { errs = append(errs, err) }}
+	conf.Check("main", imp.fset, files, nil)
+	return errs
+}
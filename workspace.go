@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Cacsjep/goxisbuilder/pkg/builder"
+)
+
+// discoverApps returns every directory below root that follows the single
+// application layout. Hidden, '_' prefixed and build directories are skipped,
+// and so is everything inside an application.
+func discoverApps(root string) ([]string, error) {
+	var apps []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		name := d.Name()
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "build" {
+			return filepath.SkipDir
+		}
+		if len(appLayoutProblems(path)) == 0 {
+			apps = append(apps, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return apps, err
+}
+
+// workspaceConfigs creates the build configurations of every app of a
+// workspace. The command line is parsed again for each app, like
+// options.parse does, so the app's own goxis.yaml applies while flags still
+// override it. Each app gets its own output directory below build/. The
+// human output goes to out.
+func workspaceConfigs(root string, apps []string, args []string, out io.Writer) []*builder.BuildConfiguration {
+	var configs []*builder.BuildConfiguration
+	for _, app := range apps {
+		fs := flag.NewFlagSet("build", flag.ExitOnError)
+		o := &options{out: out}
+		o.addAllFlags(fs)
+		parseInterspersed(fs, args)
+
+		// The app directory is part of the container path, so it needs Unix-style separators
+		o.appDirectory = filepath.ToSlash(app)
+		if _, err := applyProjectConfig(fs, o.appDirectory); err != nil {
+			handleError("Failed to load project config of "+app, err)
+		}
+
+		rel, err := filepath.Rel(root, app)
+		if err != nil {
+			handleError("Invalid application directory", err)
+		}
+		// Other apps never end up in the build context, so their changes keep the cache
+		var ignoreDirs []string
+		for _, other := range apps {
+			if other != app {
+				ignoreDirs = append(ignoreDirs, other)
+			}
+		}
+		for _, bc := range o.buildConfigurations() {
+			bc.WorkspaceApp = filepath.ToSlash(rel)
+			bc.OutputDir = filepath.Join("build", rel)
+			bc.IgnoreDirs = slices.Concat(ignoreDirs, bc.IgnoreDirs)
+			configs = append(configs, bc)
+		}
+	}
+	return configs
+}
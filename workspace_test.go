@@ -0,0 +1,131 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testAppManifest is the manifest.json of the apps of the tests.
+const testAppManifest = `{
+  "schemaVersion": "1.7.0",
+  "acapPackageConf": {
+    "setup": {
+      "appName": "testapp",
+      "vendor": "Acme",
+      "runMode": "respawn",
+      "version": "1.2.3"
+    }
+  }
+}
+`
+
+// writeTestFiles creates the files of a map from slash separated paths to
+// content below dir.
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testApp returns the files of an app in the single application layout below dir.
+func testApp(dir string) map[string]string {
+	return map[string]string{
+		dir + "/go.mod":        "module example.com/app\n",
+		dir + "/LICENSE":       "MIT\n",
+		dir + "/manifest.json": testAppManifest,
+		dir + "/main.go":       "package main\n",
+	}
+}
+
+func TestDiscoverApps(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{"app", testApp("a"), []string{"a"}},
+		{"no manifest", without(testApp("a"), "a/manifest.json"), nil},
+		{"no license", without(testApp("a"), "a/LICENSE"), nil},
+		{"no Go files", without(testApp("a"), "a/main.go"), nil},
+		{"no go.mod", without(testApp("a"), "a/go.mod"), nil},
+		{"nested", merge(testApp("apps/a"), testApp("apps/b/c")), []string{"apps/a", "apps/b/c"}},
+		{"inside an app", merge(testApp("a"), testApp("a/tools")), []string{"a"}},
+		{"skipped directories", merge(testApp(".hidden"), testApp("_draft"), testApp("build"), testApp("x/build")), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFiles(t, root, tt.files)
+			apps, err := discoverApps(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, app := range apps {
+				rel, _ := filepath.Rel(root, app)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("discoverApps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspaceConfigs(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTestFiles(t, root, merge(testApp("a"), testApp("nested/b"), map[string]string{
+		"a/" + projectConfigFile:        "arch: armv7hf\ntags: fromfile\n",
+		"nested/b/" + projectConfigFile: "tags: fromfile\n",
+	}))
+	apps := []string{filepath.Join(root, "a"), filepath.Join(root, "nested", "b")}
+
+	configs := workspaceConfigs(root, apps, []string{"-arch", "aarch64", "-notices=false"}, io.Discard)
+	if len(configs) != 2 {
+		t.Fatalf("got %d configurations, want 2", len(configs))
+	}
+	for i, want := range []struct{ app, output, other string }{
+		{"a", filepath.Join("build", "a"), apps[1]},
+		{"nested/b", filepath.Join("build", "nested", "b"), apps[0]},
+	} {
+		bc := configs[i]
+		if bc.WorkspaceApp != want.app || bc.OutputDir != want.output || bc.AppDirectory != filepath.ToSlash(apps[i]) {
+			t.Errorf("app %s in %s with output %s, want %s with output %s", bc.WorkspaceApp, bc.AppDirectory, bc.OutputDir, want.app, want.output)
+		}
+		// The flag overrides the goxis.yaml of a, the one of b still applies
+		if bc.Arch != "aarch64" || bc.BuildTags != "fromfile" || bc.Notices {
+			t.Errorf("app %s: arch %s, tags %q, notices %v, want the flags over goxis.yaml", bc.WorkspaceApp, bc.Arch, bc.BuildTags, bc.Notices)
+		}
+		if !slices.Contains(bc.IgnoreDirs, want.other) {
+			t.Errorf("app %s ignores %v, want the other app %s", bc.WorkspaceApp, bc.IgnoreDirs, want.other)
+		}
+	}
+}
+
+// merge returns the union of file maps.
+func merge(maps ...map[string]string) map[string]string {
+	files := make(map[string]string)
+	for _, m := range maps {
+		for name, content := range m {
+			files[name] = content
+		}
+	}
+	return files
+}
+
+// without returns files without name.
+func without(files map[string]string, name string) map[string]string {
+	delete(files, name)
+	return files
+}